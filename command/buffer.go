@@ -0,0 +1,48 @@
+package command
+
+import "sync"
+
+// exitErrorStderrLimit caps the stderr stored on exec.ExitError, in line with exec.Cmd.Output.
+const exitErrorStderrLimit = 64 << 10
+
+// tailBuffer is an io.Writer which keeps only the last limit bytes written to it.
+type tailBuffer struct {
+	limit     int
+	buf       []byte
+	truncated bool
+	mux       sync.Mutex
+}
+
+func newTailBuffer(limit int) *tailBuffer {
+	return &tailBuffer{limit: limit}
+}
+
+// Write implements io.Writer.
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if b.limit > 0 && len(b.buf) > b.limit {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-b.limit:]...)
+		b.truncated = true
+	}
+
+	return len(p), nil
+}
+
+// Bytes returns a copy of the retained bytes.
+func (b *tailBuffer) Bytes() []byte {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return append([]byte(nil), b.buf...)
+}
+
+// Truncated reports whether bytes were dropped because of the limit.
+func (b *tailBuffer) Truncated() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.truncated
+}
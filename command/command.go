@@ -1,15 +1,34 @@
 package command
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bitrise-io/go-utils/v2/env"
 )
 
+const (
+	// DefaultTerminateGracePeriod is the time a cancelled command is given to exit after SIGTERM before it gets killed.
+	DefaultTerminateGracePeriod = 10 * time.Second
+	// DefaultHangOutputLines is the number of last output lines attached to a HangError.
+	DefaultHangOutputLines = 20
+
+	// DefaultResultOutputLimit is the number of bytes retained of each output stream of a Result.
+	DefaultResultOutputLimit = 1 << 20
+
+	// descendantsOutputDelay is the time Wait waits for the descendants of a process group leader
+	// to close the output pipes after the leader exited. The descendants are killed afterwards.
+	descendantsOutputDelay = time.Second
+)
+
 // ErrorFinder ...
 type ErrorFinder func(out string) []string
 
@@ -21,11 +40,67 @@ type Opts struct {
 	Env         []string
 	Dir         string
 	ErrorFinder ErrorFinder
+	// LineBufferedErrorFinder makes ErrorFinder receive complete lines only,
+	// by default it is called with the chunks written by the command, which may split lines.
+	LineBufferedErrorFinder bool
+	// ErrorMatcher is fed with the command's output line by line, its matches are reported in ExitStatusError
+	// after the lines found by ErrorFinder.
+	ErrorMatcher ErrorMatcher
+	// EnvMode controls the environment variables inherited from the Factory's env.Repository,
+	// Env is merged on top of them (see ResolveEnv).
+	EnvMode EnvMode
+	// EnvKeys lists the keys of the inherited variables in EnvAllowList mode, or the excluded ones in EnvDenyList mode.
+	EnvKeys []string
+	// TerminateGracePeriod is the time a command is given to exit after SIGTERM when its context is done,
+	// before SIGKILL is sent. Defaults to DefaultTerminateGracePeriod.
+	TerminateGracePeriod time.Duration
+	// Timeout stops the command (see TerminateGracePeriod) when it runs longer than the given duration.
+	Timeout time.Duration
+	// NoOutputTimeout stops the command when it does not write to stdout or stderr for the given duration,
+	// the command returns a *HangError in this case.
+	NoOutputTimeout time.Duration
+	// HangOutputLines is the number of last output lines attached to a HangError.
+	// Defaults to DefaultHangOutputLines.
+	HangOutputLines int
+	// ProcessGroup runs the command in its own process group (Setpgid on unix systems),
+	// Terminate and Kill stop the whole group and Wait kills the processes left in the group after the command exits.
+	// Without it stopping the command (cancelled context, Timeout, NoOutputTimeout) signals only the process itself,
+	// so its descendants may keep running, Wait waits at most TerminateGracePeriod + 1s for them to close the output pipes
+	// after the process exited. The process group is opt-in, as a command outside of the foreground
+	// process group cannot read the terminal and does not receive Ctrl-C.
+	ProcessGroup bool
+	// ResultOutputLimit caps the number of bytes retained of each output stream by RunWithResult,
+	// only the last ResultOutputLimit bytes are kept. Defaults to DefaultResultOutputLimit.
+	ResultOutputLimit int
+	// Secrets are replaced with redactwriter.RedactStr in the arguments printed by PrintableCommandArgs
+	// and so in the errors returned by the command, and in the Expect transcript.
+	Secrets []string
+	// UsePTY runs the command under a pseudo-terminal (Linux only), for tools which behave differently without a TTY
+	// (no colors or progress output). The process' stdin, stdout and stderr are attached to the terminal:
+	// Stdin is forwarded to it, and the merged output of the terminal is written to Stdout, Stderr is not used.
+	// The command runs in its own session and process group.
+	UsePTY bool
+	// PTYSize is the window size of the pseudo-terminal, the zero fields default to DefaultPTYRows and DefaultPTYCols.
+	PTYSize PTYSize
+	// Expect answers the prompts of an interactive command: it waits for the output expected by its steps
+	// and writes the responses to the command's stdin. It cannot be used together with Stdin.
+	Expect *Expect
+	// Limits caps the resources of the command (Linux only), a command killed for exceeding a limit
+	// returns a *LimitExceededError.
+	Limits *Limits
+	// LogFile mirrors stdout and stderr of the command into a log file, besides the configured writers.
+	// The path of the file is reported by ExitStatusError.LogFile.
+	LogFile *LogFileOpts
 }
 
 // Factory ...
 type Factory interface {
 	Create(name string, args []string, opts *Opts) Command
+	// CreateWithContext creates a command which is stopped when ctx is done: on cancellation the process
+	// receives SIGTERM, and SIGKILL after the grace period (Opts.TerminateGracePeriod) is elapsed.
+	// Only the process itself is signalled, its descendants keep running unless Opts.ProcessGroup is set,
+	// which makes the whole process group receive the signals.
+	CreateWithContext(ctx context.Context, name string, args []string, opts *Opts) Command
 }
 
 type factory struct {
@@ -33,34 +108,53 @@ type factory struct {
 }
 
 // NewFactory ...
+// The commands inherit the variables of envRepository (see Opts.EnvMode) instead of the process environment.
 func NewFactory(envRepository env.Repository) Factory {
 	return factory{envRepository: envRepository}
 }
 
 // Create ...
 func (f factory) Create(name string, args []string, opts *Opts) Command {
-	cmd := exec.Command(name, args...)
+	return f.CreateWithContext(context.Background(), name, args, opts)
+}
+
+// CreateWithContext ...
+func (f factory) CreateWithContext(ctx context.Context, name string, args []string, opts *Opts) Command {
 	var collector *errorCollector
+	var cmdOpts Opts
+	// The environment always comes from the repository, so commands inherit in-memory and layered repositories too.
+	cmdEnv := ResolveEnv(f.envRepository, opts)
 
 	if opts != nil {
-		if opts.ErrorFinder != nil {
-			collector = &errorCollector{errorFinder: opts.ErrorFinder}
+		cmdOpts = *opts
+
+		if opts.ErrorFinder != nil || opts.ErrorMatcher != nil {
+			collector = &errorCollector{
+				errorFinder:  opts.ErrorFinder,
+				lineBuffered: opts.LineBufferedErrorFinder,
+				matcher:      opts.ErrorMatcher,
+			}
 		}
 
-		cmd.Stdout = opts.Stdout
-		cmd.Stderr = opts.Stderr
-		cmd.Stdin = opts.Stdin
+	}
 
-		// If Env is nil, the new process uses the current process's
-		// environment.
-		// If we pass env vars we want to append them to the
-		// current process's environment.
-		cmd.Env = append(f.envRepository.List(), opts.Env...)
-		cmd.Dir = opts.Dir
+	// exec.Cmd is single-use, newCmd recreates it for every run of the command.
+	newCmd := func() *exec.Cmd {
+		cmd := exec.Command(name, args...)
+		cmd.Stdout = cmdOpts.Stdout
+		cmd.Stderr = cmdOpts.Stderr
+		cmd.Stdin = cmdOpts.Stdin
+		cmd.Env = cmdEnv
+		cmd.Dir = cmdOpts.Dir
+		return cmd
 	}
+
 	return &command{
-		cmd:            cmd,
+		cmd:            newCmd(),
+		newCmd:         newCmd,
 		errorCollector: collector,
+		ctx:            ctx,
+		opts:           cmdOpts,
 	}
 }
 
@@ -71,25 +165,78 @@ type Command interface {
 	RunAndReturnExitCode() (int, error)
 	RunAndReturnTrimmedOutput() (string, error)
 	RunAndReturnTrimmedCombinedOutput() (string, error)
+	// RunWithResult runs the command and returns the details of the execution.
+	// The Result is returned even if the command fails, the error is the same as Run would return.
+	RunWithResult() (*Result, error)
 	Start() error
 	Wait() error
+	// Terminate stops a started command gracefully: sends SIGTERM and SIGKILL after Opts.TerminateGracePeriod.
+	Terminate() error
+	// Kill stops a started command immediately.
+	Kill() error
 }
 
 type command struct {
-	cmd            *exec.Cmd
+	cmd *exec.Cmd
+	// newCmd recreates cmd for another run, nil if the command can run only once.
+	newCmd         func() *exec.Cmd
+	used           bool
 	errorCollector *errorCollector
+	ctx            context.Context
+	opts           Opts
+
+	// stdoutTee and stderrTee receive the outputs besides the configured writers, set by Group.
+	stdoutTee io.Writer
+	stderrTee io.Writer
+
+	logFile   *rotatingFile
+	expect    *expectSession
+	watchdog  *watchdog
+	execution *execution
+}
+
+// execution holds the state of a started process.
+type execution struct {
+	done         chan struct{}
+	processGroup bool
+	cancel       context.CancelFunc
+	pty          *ptySession
+	limits       *limitSession
+	limitErr     LimitResource
+	doneOnce     sync.Once
+
+	mux     sync.Mutex
+	stopErr error
+}
+
+// finish marks the execution as done, once.
+func (e *execution) finish() {
+	e.doneOnce.Do(func() {
+		close(e.done)
+	})
+}
+
+// finished reports whether the command has already been waited for.
+func (e *execution) finished() bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
 }
 
 // PrintableCommandArgs ...
-func (c command) PrintableCommandArgs() string {
-	return printableCommandArgs(false, c.cmd.Args)
+func (c *command) PrintableCommandArgs() string {
+	return printableCommandArgs(false, c.cmd.Args, c.opts.Secrets)
 }
 
 // Run ...
 func (c *command) Run() error {
+	c.prepare()
 	c.wrapOutputs()
 
-	if err := c.cmd.Run(); err != nil {
+	if err := c.run(); err != nil {
 		return c.wrapError(err)
 	}
 
@@ -97,9 +244,10 @@ func (c *command) Run() error {
 }
 
 // RunAndReturnExitCode ...
-func (c command) RunAndReturnExitCode() (int, error) {
+func (c *command) RunAndReturnExitCode() (int, error) {
+	c.prepare()
 	c.wrapOutputs()
-	err := c.cmd.Run()
+	err := c.run()
 	if err != nil {
 		err = c.wrapError(err)
 	}
@@ -109,12 +257,13 @@ func (c command) RunAndReturnExitCode() (int, error) {
 }
 
 // RunAndReturnTrimmedOutput ...
-func (c command) RunAndReturnTrimmedOutput() (string, error) {
-	outBytes, err := c.cmd.Output()
+func (c *command) RunAndReturnTrimmedOutput() (string, error) {
+	c.prepare()
+	outBytes, err := c.output()
 	outStr := string(outBytes)
 	if err != nil {
 		if c.errorCollector != nil {
-			c.errorCollector.collectErrors(outStr)
+			c.errorCollector.collectOutput(outStr)
 		}
 		err = c.wrapError(err)
 	}
@@ -123,12 +272,13 @@ func (c command) RunAndReturnTrimmedOutput() (string, error) {
 }
 
 // RunAndReturnTrimmedCombinedOutput ...
-func (c command) RunAndReturnTrimmedCombinedOutput() (string, error) {
-	outBytes, err := c.cmd.CombinedOutput()
+func (c *command) RunAndReturnTrimmedCombinedOutput() (string, error) {
+	c.prepare()
+	outBytes, err := c.combinedOutput()
 	outStr := string(outBytes)
 	if err != nil {
 		if c.errorCollector != nil {
-			c.errorCollector.collectErrors(outStr)
+			c.errorCollector.collectOutput(outStr)
 		}
 		err = c.wrapError(err)
 	}
@@ -137,14 +287,19 @@ func (c command) RunAndReturnTrimmedCombinedOutput() (string, error) {
 }
 
 // Start ...
-func (c command) Start() error {
+func (c *command) Start() error {
+	c.prepare()
 	c.wrapOutputs()
-	return c.cmd.Start()
+	if err := c.start(); err != nil {
+		return c.wrapError(err)
+	}
+
+	return nil
 }
 
 // Wait ...
-func (c command) Wait() error {
-	err := c.cmd.Wait()
+func (c *command) Wait() error {
+	err := c.wait()
 	if err != nil {
 		err = c.wrapError(err)
 	}
@@ -152,49 +307,429 @@ func (c command) Wait() error {
 	return err
 }
 
-func printableCommandArgs(isQuoteFirst bool, fullCommandArgs []string) string {
-	var cmdArgsDecorated []string
-	for idx, anArg := range fullCommandArgs {
-		quotedArg := fmt.Sprintf("\"%s\"", anArg)
-		if idx == 0 && !isQuoteFirst {
-			quotedArg = anArg
+// Terminate ...
+func (c *command) Terminate() error {
+	if c.execution == nil {
+		return errors.New("command not started")
+	}
+
+	return c.terminate(c.execution, ErrTerminated)
+}
+
+// Kill ...
+func (c *command) Kill() error {
+	if c.execution == nil {
+		return errors.New("command not started")
+	}
+
+	c.execution.stop(ErrTerminated)
+	return killProcess(c.cmd.Process, c.execution.processGroup)
+}
+
+// prepare makes the command ready for the next run, it recreates the exec.Cmd if the command already ran.
+// A Stdin implementing io.Seeker is rewound.
+func (c *command) prepare() {
+	if !c.used || c.newCmd == nil {
+		c.used = true
+		return
+	}
+
+	c.cmd = c.newCmd()
+	if seeker, ok := c.cmd.Stdin.(io.Seeker); ok {
+		_, _ = seeker.Seek(0, io.SeekStart)
+	}
+	if c.errorCollector != nil {
+		c.errorCollector = c.errorCollector.reset()
+	}
+	c.logFile = nil
+	c.expect = nil
+	c.watchdog = nil
+	c.execution = nil
+}
+
+func (c *command) run() error {
+	if err := c.start(); err != nil {
+		return err
+	}
+	return c.wait()
+}
+
+// output mirrors exec.Cmd.Output, but runs the process through start and wait.
+func (c *command) output() ([]byte, error) {
+	if c.cmd.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.cmd.Stdout = &stdout
+
+	var stderr *tailBuffer
+	if c.cmd.Stderr == nil {
+		stderr = newTailBuffer(exitErrorStderrLimit)
+		c.cmd.Stderr = stderr
+	}
+	c.watchOutputs()
+
+	err := c.run()
+	var exitErr *exec.ExitError
+	if stderr != nil && errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+	}
+
+	return stdout.Bytes(), err
+}
+
+// combinedOutput mirrors exec.Cmd.CombinedOutput, but runs the process through start and wait.
+func (c *command) combinedOutput() ([]byte, error) {
+	if c.cmd.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	if c.cmd.Stderr != nil {
+		return nil, errors.New("exec: Stderr already set")
+	}
+	var b bytes.Buffer
+	c.cmd.Stdout = &b
+	c.cmd.Stderr = &b
+	c.watchOutputs()
+
+	err := c.run()
+	return b.Bytes(), err
+}
+
+func (c *command) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+func (c *command) start() error {
+	ctx := c.context()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	e := &execution{done: make(chan struct{})}
+	// cleanups release the resources of the execution if the process fails to start.
+	var cleanups []func()
+	fail := func(err error) error {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+		return err
+	}
+
+	if c.opts.Timeout > 0 {
+		ctx, e.cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		cleanups = append(cleanups, e.cancel)
+	}
+
+	if c.logFile != nil {
+		if err := c.logFile.open(); err != nil {
+			return fail(fmt.Errorf("failed to open log file: %w", err))
+		}
+		cleanups = append(cleanups, func() { _ = c.logFile.Close() })
+	}
+
+	var stdinReader *os.File
+	if c.expect != nil {
+		var err error
+		if stdinReader, err = c.expect.open(c.cmd.Stdin); err != nil {
+			return fail(err)
+		}
+		c.cmd.Stdin = stdinReader
+		cleanups = append(cleanups, c.expect.stop)
+	}
+
+	if c.opts.Limits != nil {
+		var err error
+		if e.limits, err = newLimitSession(*c.opts.Limits, c.cmd); err != nil {
+			return fail(err)
+		}
+		cleanups = append(cleanups, e.limits.release)
+	}
+
+	var tty *os.File
+	if c.opts.UsePTY {
+		var err error
+		if e.pty, tty, err = c.attachPTY(); err != nil {
+			return fail(fmt.Errorf("failed to open PTY: %w", err))
+		}
+		cleanups = append(cleanups, e.pty.close)
+		// The session leader started by setControllingTerminal leads its own process group.
+		e.processGroup = true
+	} else if c.opts.ProcessGroup {
+		e.processGroup = true
+		setProcessGroup(c.cmd)
+		if c.cmd.WaitDelay == 0 {
+			c.cmd.WaitDelay = descendantsOutputDelay
+		}
+	} else if c.cmd.WaitDelay == 0 && (ctx.Done() != nil || c.watchdog != nil || c.expect != nil) {
+		// Stopping the command signals only the process itself, its descendants may keep the output pipes open.
+		c.cmd.WaitDelay = c.waitDelay()
+	}
+
+	err := c.cmd.Start()
+	if e.limits != nil {
+		e.limits.started(c.cmd)
+	}
+	// The process holds its own copy of the terminal and of the stdin pipe,
+	// the pipe is read by the parent when forwarded to the PTY.
+	if tty != nil {
+		_ = tty.Close()
+	} else if stdinReader != nil {
+		_ = stdinReader.Close()
+	}
+	if err != nil {
+		return fail(err)
+	}
+	c.execution = e
+
+	if ctx.Done() != nil {
+		go c.watch(ctx, e)
+	}
+	if c.watchdog != nil {
+		c.watchdog.start(func(hangErr *HangError) {
+			hangErr.printableCmdArgs = c.PrintableCommandArgs()
+			_ = c.terminate(e, hangErr)
+		})
+	}
+	if c.expect != nil {
+		go c.expect.run(func(expectErr *ExpectError) {
+			expectErr.printableCmdArgs = c.PrintableCommandArgs()
+			_ = c.terminate(e, expectErr)
+		})
+	}
+
+	return nil
+}
+
+func (c *command) wait() error {
+	if c.execution != nil && c.execution.finished() {
+		// exec.Cmd returns its "Wait was already called" error.
+		return c.cmd.Wait()
+	}
+
+	err := c.cmd.Wait()
+	if c.execution != nil && c.execution.processGroup {
+		// Descendants which are still running would outlive the command, stop them.
+		_ = killProcess(c.cmd.Process, true)
+		if errors.Is(err, exec.ErrWaitDelay) {
+			err = nil
+		}
+	}
+	if c.execution != nil && c.execution.pty != nil {
+		c.execution.pty.wait(descendantsOutputDelay)
+	}
+	if c.execution != nil && c.execution.limits != nil {
+		// A process stopped by the command (like on Timeout) may be killed after using up its CPU time,
+		// the stop reason is reported instead.
+		if c.execution.stopReason() == nil {
+			c.execution.limitErr = c.execution.limits.exceeded(c.cmd.ProcessState)
+		}
+		c.execution.limits.release()
+	}
+	if c.watchdog != nil {
+		c.watchdog.stop()
+	}
+	if c.expect != nil {
+		c.expect.stop()
+	}
+	if c.logFile != nil {
+		// A broken log file does not fail the command.
+		_ = c.logFile.Close()
+	}
+	if c.errorCollector != nil {
+		c.errorCollector.flush()
+	}
+	if c.execution != nil {
+		c.execution.finish()
+		if c.execution.cancel != nil {
+			c.execution.cancel()
+		}
+	}
+
+	return err
+}
+
+// watch stops the process when ctx is done before the process exits.
+func (c *command) watch(ctx context.Context, e *execution) {
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		_ = c.terminate(e, ctx.Err())
+	}
+}
+
+// waitDelay is the time Wait waits for the descendants of a stoppable command outside of a process group
+// to close the output pipes after the command exited: they are not signalled, so they get the grace period too.
+func (c *command) waitDelay() time.Duration {
+	return c.gracePeriod() + descendantsOutputDelay
+}
+
+func (c *command) gracePeriod() time.Duration {
+	if c.opts.TerminateGracePeriod <= 0 {
+		return DefaultTerminateGracePeriod
+	}
+	return c.opts.TerminateGracePeriod
+}
+
+// terminate sends SIGTERM to the process (group) and SIGKILL if it does not exit within the grace period.
+// reason is reported by wrapError instead of the process' own exit status.
+func (c *command) terminate(e *execution, reason error) error {
+	if !e.stop(reason) {
+		return nil
+	}
+
+	if err := terminateProcess(c.cmd.Process, e.processGroup); err != nil {
+		return err
+	}
+
+	go func() {
+		timer := time.NewTimer(c.gracePeriod())
+		defer timer.Stop()
+
+		select {
+		case <-e.done:
+		case <-timer.C:
+			_ = killProcess(c.cmd.Process, e.processGroup)
 		}
-		cmdArgsDecorated = append(cmdArgsDecorated, quotedArg)
+	}()
+
+	return nil
+}
+
+// stop records the reason of stopping the process, returns false if the process is already being stopped.
+func (e *execution) stop(reason error) bool {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if e.stopErr != nil {
+		return false
 	}
+	e.stopErr = reason
+	return true
+}
+
+func (e *execution) stopReason() error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
 
-	return strings.Join(cmdArgsDecorated, " ")
+	return e.stopErr
 }
 
-func (c command) wrapError(err error) error {
+func (c *command) wrapError(err error) error {
+	if c.execution != nil {
+		if reason := c.execution.stopReason(); reason != nil {
+			err = reason
+		}
+	}
+	return c.wrapProcessError(err)
+}
+
+// wrapProcessError wraps err without replacing it with the reason the process was stopped for.
+func (c *command) wrapProcessError(err error) error {
+	var hangErr *HangError
+	var expectErr *ExpectError
+	switch {
+	case errors.As(err, &hangErr):
+		return hangErr
+	case errors.As(err, &expectErr):
+		return expectErr
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w (%s): %w", ErrTimedOut, c.PrintableCommandArgs(), err)
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%w (%s): %w", ErrCancelled, c.PrintableCommandArgs(), err)
+	case errors.Is(err, ErrTerminated):
+		return fmt.Errorf("%w (%s)", ErrTerminated, c.PrintableCommandArgs())
+	}
+
 	var exitErr *exec.ExitError
 	if errors.As(err, &exitErr) {
 		errorLines := []string{}
 		if c.errorCollector != nil {
-			errorLines = c.errorCollector.errorLines
+			errorLines = c.errorCollector.errors()
 		}
 
-		return NewExitStatusError(c.PrintableCommandArgs(), exitErr, errorLines)
+		exitStatusErr := NewExitStatusError(c.PrintableCommandArgs(), exitErr, errorLines).(*ExitStatusError)
+		if c.opts.LogFile != nil {
+			exitStatusErr.logFile = c.opts.LogFile.Path
+		}
+		if c.execution != nil && c.execution.limitErr != "" {
+			return &LimitExceededError{
+				Resource:         c.execution.limitErr,
+				printableCmdArgs: c.PrintableCommandArgs(),
+				exitErr:          exitStatusErr,
+			}
+		}
+		return exitStatusErr
 	}
 
 	return fmt.Errorf("executing command failed (%s): %w", c.PrintableCommandArgs(), err)
 }
 
-func (c command) wrapOutputs() {
+func (c *command) wrapOutputs() {
+	defer c.watchOutputs()
+
 	if c.errorCollector == nil {
 		return
 	}
 
 	if c.cmd.Stdout != nil {
-		outWriter := io.MultiWriter(c.errorCollector, c.cmd.Stdout)
+		outWriter := io.MultiWriter(c.errorCollector.writer(), c.cmd.Stdout)
 		c.cmd.Stdout = outWriter
 	} else {
-		c.cmd.Stdout = c.errorCollector
+		c.cmd.Stdout = c.errorCollector.writer()
 	}
 
 	if c.cmd.Stderr != nil {
-		errWriter := io.MultiWriter(c.errorCollector, c.cmd.Stderr)
+		errWriter := io.MultiWriter(c.errorCollector.writer(), c.cmd.Stderr)
 		c.cmd.Stderr = errWriter
 	} else {
-		c.cmd.Stderr = c.errorCollector
+		c.cmd.Stderr = c.errorCollector.writer()
+	}
+}
+
+// watchOutputs mirrors the (already wrapped) outputs into the tees and the log file,
+// and makes them observed by the expect session and the no output watchdog.
+func (c *command) watchOutputs() {
+	if c.stdoutTee != nil {
+		c.cmd.Stdout = teeWriter(c.cmd.Stdout, c.stdoutTee)
+	}
+	if c.stderrTee != nil {
+		c.cmd.Stderr = teeWriter(c.cmd.Stderr, c.stderrTee)
+	}
+
+	if c.opts.LogFile != nil {
+		c.logFile = newRotatingFile(*c.opts.LogFile)
+		c.cmd.Stdout = teeWriter(c.cmd.Stdout, c.logFile)
+		c.cmd.Stderr = teeWriter(c.cmd.Stderr, c.logFile)
+	}
+
+	if c.opts.Expect != nil {
+		c.expect = newExpectSession(*c.opts.Expect, c.opts.Secrets)
+		c.cmd.Stdout = teeWriter(c.cmd.Stdout, c.expect)
+		c.cmd.Stderr = teeWriter(c.cmd.Stderr, c.expect)
+	}
+
+	if c.opts.NoOutputTimeout <= 0 {
+		return
+	}
+
+	lines := c.opts.HangOutputLines
+	if lines <= 0 {
+		lines = DefaultHangOutputLines
+	}
+	c.watchdog = newWatchdog(c.opts.NoOutputTimeout, lines)
+
+	if c.cmd.Stdout != nil {
+		c.cmd.Stdout = io.MultiWriter(c.watchdog, c.cmd.Stdout)
+	} else {
+		c.cmd.Stdout = c.watchdog
+	}
+
+	if c.cmd.Stderr != nil {
+		c.cmd.Stderr = io.MultiWriter(c.watchdog, c.cmd.Stderr)
+	} else {
+		c.cmd.Stderr = c.watchdog
 	}
 }
@@ -2,14 +2,17 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bitrise-io/go-utils/v2/env"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRunErrors(t *testing.T) {
@@ -264,3 +267,64 @@ func TestSpecialCharactersAreNotEscaped(t *testing.T) {
 
 	assert.Equal(t, expected, got)
 }
+
+func TestCreateWithContext(t *testing.T) {
+	factory := NewFactory(env.NewRepository())
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cmd := factory.CreateWithContext(ctx, "sleep", []string{"10"}, nil)
+		time.AfterFunc(100*time.Millisecond, cancel)
+
+		err := cmd.Run()
+		require.ErrorIs(t, err, ErrCancelled)
+		require.ErrorIs(t, err, context.Canceled)
+		require.EqualError(t, err, `command cancelled (sleep "10"): context canceled`)
+	})
+
+	t.Run("timed out", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		cmd := factory.CreateWithContext(ctx, "sleep", []string{"10"}, nil)
+
+		exitCode, err := cmd.RunAndReturnExitCode()
+		require.ErrorIs(t, err, ErrTimedOut)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, -1, exitCode)
+	})
+
+	t.Run("already cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		cmd := factory.CreateWithContext(ctx, "sleep", []string{"10"}, nil)
+
+		require.ErrorIs(t, cmd.Run(), ErrCancelled)
+	})
+
+	t.Run("killed after grace period", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		cmd := factory.CreateWithContext(ctx, "bash", []string{"-c", "trap '' TERM; sleep 10"}, &Opts{TerminateGracePeriod: 200 * time.Millisecond})
+
+		start := time.Now()
+		require.ErrorIs(t, cmd.Run(), ErrTimedOut)
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("exit status error is not masked", func(t *testing.T) {
+		cmd := factory.CreateWithContext(context.Background(), "bash", []string{"testdata/exit_42.sh"}, nil)
+
+		err := cmd.Run()
+		var exitStatusErr *ExitStatusError
+		require.ErrorAs(t, err, &exitStatusErr)
+	})
+}
+
+func TestWaitAfterRun(t *testing.T) {
+	cmd := NewFactory(env.NewRepository()).Create("true", nil, nil)
+	require.NoError(t, cmd.Run())
+
+	err := cmd.Wait()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Wait was already called")
+}
@@ -0,0 +1,196 @@
+package commandtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+)
+
+type fakeCommand struct {
+	factory *Factory
+	ctx     context.Context
+	name    string
+	args    []string
+	opts    command.Opts
+
+	started  bool
+	startErr error
+}
+
+// PrintableCommandArgs ...
+func (c *fakeCommand) PrintableCommandArgs() string {
+	return command.PrintableCommandArgs(c.name, c.args, c.opts.Secrets)
+}
+
+// Run ...
+func (c *fakeCommand) Run() error {
+	_, err := c.execute(c.opts.Stdout, c.opts.Stderr, false)
+	return err
+}
+
+// RunAndReturnExitCode ...
+func (c *fakeCommand) RunAndReturnExitCode() (int, error) {
+	return c.execute(c.opts.Stdout, c.opts.Stderr, false)
+}
+
+// RunAndReturnTrimmedOutput ...
+func (c *fakeCommand) RunAndReturnTrimmedOutput() (string, error) {
+	if c.opts.Stdout != nil {
+		return "", fmt.Errorf("executing command failed (%s): exec: Stdout already set", c.PrintableCommandArgs())
+	}
+
+	var stdout bytes.Buffer
+	_, err := c.execute(&stdout, c.opts.Stderr, c.opts.Stderr == nil)
+	return strings.TrimSpace(stdout.String()), err
+}
+
+// RunAndReturnTrimmedCombinedOutput ...
+func (c *fakeCommand) RunAndReturnTrimmedCombinedOutput() (string, error) {
+	if c.opts.Stdout != nil || c.opts.Stderr != nil {
+		return "", fmt.Errorf("executing command failed (%s): exec: Stdout or Stderr already set", c.PrintableCommandArgs())
+	}
+
+	var combined bytes.Buffer
+	_, err := c.execute(&combined, &combined, false)
+	return strings.TrimSpace(combined.String()), err
+}
+
+// RunWithResult ...
+func (c *fakeCommand) RunWithResult() (*command.Result, error) {
+	var stdout, stderr, combined bytes.Buffer
+	result := &command.Result{StartTime: time.Now()}
+
+	exitCode, err := c.execute(teeWriter(c.opts.Stdout, &stdout, &combined), teeWriter(c.opts.Stderr, &stderr, &combined), false)
+
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	result.ExitCode = exitCode
+	result.Stdout = stdout.Bytes()
+	result.Stderr = stderr.Bytes()
+	result.CombinedOutput = combined.Bytes()
+	return result, err
+}
+
+// Start runs the fake command, its error is returned by Wait.
+func (c *fakeCommand) Start() error {
+	c.started = true
+	_, c.startErr = c.execute(c.opts.Stdout, c.opts.Stderr, false)
+	return nil
+}
+
+// Wait ...
+func (c *fakeCommand) Wait() error {
+	if !c.started {
+		return fmt.Errorf("executing command failed (%s): exec: not started", c.PrintableCommandArgs())
+	}
+	return c.startErr
+}
+
+// Terminate is a no-op, fake commands finish in Start.
+func (c *fakeCommand) Terminate() error {
+	if !c.started {
+		return errors.New("command not started")
+	}
+	return nil
+}
+
+// Kill is a no-op, fake commands finish in Start.
+func (c *fakeCommand) Kill() error {
+	return c.Terminate()
+}
+
+// execute records the invocation and replays the matching stub.
+// stderrAsErrorOutput mimics exec.Cmd.Output, which reports the stderr in the error.
+func (c *fakeCommand) execute(stdout, stderr io.Writer, stderrAsErrorOutput bool) (int, error) {
+	if c.ctx != nil && c.ctx.Err() != nil {
+		if errors.Is(c.ctx.Err(), context.DeadlineExceeded) {
+			return -1, fmt.Errorf("%w (%s): %w", command.ErrTimedOut, c.PrintableCommandArgs(), c.ctx.Err())
+		}
+		return -1, fmt.Errorf("%w (%s): %w", command.ErrCancelled, c.PrintableCommandArgs(), c.ctx.Err())
+	}
+
+	invocation := Invocation{
+		Name: c.name,
+		Args: append([]string(nil), c.args...),
+		Env:  append([]string(nil), c.opts.Env...),
+		Dir:  c.opts.Dir,
+	}
+	if c.opts.Stdin != nil {
+		in, err := io.ReadAll(c.opts.Stdin)
+		if err != nil {
+			return -1, fmt.Errorf("executing command failed (%s): reading stdin: %w", c.PrintableCommandArgs(), err)
+		}
+		invocation.Stdin = string(in)
+	}
+
+	stub := c.factory.record(invocation)
+	if stub == nil {
+		return -1, fmt.Errorf("executing command failed (%s): %w", c.PrintableCommandArgs(), errNoStub{commandLine: invocation.String()})
+	}
+	if stub.startErr != nil {
+		return -1, fmt.Errorf("executing command failed (%s): %w", c.PrintableCommandArgs(), stub.startErr)
+	}
+
+	if err := write(stdout, stub.stdout); err != nil {
+		return -1, fmt.Errorf("executing command failed (%s): %w", c.PrintableCommandArgs(), err)
+	}
+	if err := write(stderr, stub.stderr); err != nil {
+		return -1, fmt.Errorf("executing command failed (%s): %w", c.PrintableCommandArgs(), err)
+	}
+
+	if stub.exitCode == 0 {
+		return 0, nil
+	}
+
+	errorLines := c.errorLines(stub)
+	if len(errorLines) == 0 && stderrAsErrorOutput && stub.stderr != "" {
+		errorLines = []string{stub.stderr}
+	}
+	return stub.exitCode, command.NewExitStatusErrorFromCode(c.PrintableCommandArgs(), stub.exitCode, errorLines)
+}
+
+// errorLines applies the ErrorFinder and ErrorMatcher of the command on the scripted output.
+func (c *fakeCommand) errorLines(stub *Stub) []string {
+	var lines []string
+	if c.opts.ErrorFinder != nil {
+		lines = append(lines, c.opts.ErrorFinder(stub.stdout)...)
+		lines = append(lines, c.opts.ErrorFinder(stub.stderr)...)
+	}
+	if c.opts.ErrorMatcher != nil {
+		// The matcher is shared by the runs of the command, like in the command package.
+		c.opts.ErrorMatcher.Reset()
+		for _, output := range []string{stub.stdout, stub.stderr} {
+			for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+				if line != "" {
+					c.opts.ErrorMatcher.Feed(line)
+				}
+			}
+		}
+		c.opts.ErrorMatcher.Flush()
+		for _, match := range c.opts.ErrorMatcher.Matches() {
+			lines = append(lines, match.String())
+		}
+	}
+	return lines
+}
+
+func write(w io.Writer, s string) error {
+	if w == nil || s == "" {
+		return nil
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+func teeWriter(w io.Writer, buffers ...io.Writer) io.Writer {
+	if w == nil {
+		return io.MultiWriter(buffers...)
+	}
+	return io.MultiWriter(append([]io.Writer{w}, buffers...)...)
+}
@@ -0,0 +1,211 @@
+// Package commandtest provides a fake command.Factory for testing code which executes commands,
+// without running real executables.
+package commandtest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+)
+
+// TestingT is the subset of testing.TB used by the assertions.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Invocation is a recorded run of a fake command.
+type Invocation struct {
+	Name string
+	Args []string
+	// Env holds the environment variables passed in command.Opts.Env.
+	Env []string
+	Dir string
+	// Stdin holds the content read from command.Opts.Stdin.
+	Stdin string
+}
+
+// String returns the command line of the invocation.
+func (i Invocation) String() string {
+	return commandLine(i.Name, i.Args)
+}
+
+// Stub scripts the behaviour of the commands matching its Matcher.
+type Stub struct {
+	matcher  Matcher
+	stdout   string
+	stderr   string
+	exitCode int
+	startErr error
+	times    int
+	calls    int
+}
+
+// Stdout sets the output the command writes to its stdout.
+func (s *Stub) Stdout(stdout string) *Stub {
+	s.stdout = stdout
+	return s
+}
+
+// Stderr sets the output the command writes to its stderr.
+func (s *Stub) Stderr(stderr string) *Stub {
+	s.stderr = stderr
+	return s
+}
+
+// ExitCode sets the exit code of the command, a non-zero exit code makes the command return a *command.ExitStatusError.
+func (s *Stub) ExitCode(exitCode int) *Stub {
+	s.exitCode = exitCode
+	return s
+}
+
+// StartError makes the command fail to start with err, like a missing executable.
+func (s *Stub) StartError(err error) *Stub {
+	s.startErr = err
+	return s
+}
+
+// Times limits the number of invocations the stub is used for, a later registered stub matching the same
+// commands takes over afterwards. Useful for scripting retries. The default 0 means unlimited.
+func (s *Stub) Times(times int) *Stub {
+	s.times = times
+	return s
+}
+
+func (s *Stub) available() bool {
+	return s.times == 0 || s.calls < s.times
+}
+
+// Factory is a command.Factory creating fake commands, scripted by Stubs.
+// Commands without a matching Stub fail to start. Factory is safe for concurrent use.
+type Factory struct {
+	mux         sync.Mutex
+	stubs       []*Stub
+	invocations []Invocation
+}
+
+// NewFactory ...
+func NewFactory() *Factory {
+	return &Factory{}
+}
+
+// On registers a Stub for the commands matching m. Stubs are matched in the order of registration.
+// By default, the matching commands succeed without output.
+func (f *Factory) On(m Matcher) *Stub {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	stub := &Stub{matcher: m}
+	f.stubs = append(f.stubs, stub)
+	return stub
+}
+
+// Create ...
+func (f *Factory) Create(name string, args []string, opts *command.Opts) command.Command {
+	return f.CreateWithContext(context.Background(), name, args, opts)
+}
+
+// CreateWithContext ...
+func (f *Factory) CreateWithContext(ctx context.Context, name string, args []string, opts *command.Opts) command.Command {
+	if opts == nil {
+		opts = &command.Opts{}
+	}
+	return &fakeCommand{
+		factory: f,
+		ctx:     ctx,
+		name:    name,
+		args:    args,
+		opts:    *opts,
+	}
+}
+
+// Invocations returns the recorded invocations in the order of execution.
+func (f *Factory) Invocations() []Invocation {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return append([]Invocation(nil), f.invocations...)
+}
+
+// AssertCalled asserts that a command matching m was invoked.
+func (f *Factory) AssertCalled(t TestingT, m Matcher) bool {
+	t.Helper()
+
+	for _, invocation := range f.Invocations() {
+		if m.Match(invocation.Name, invocation.Args) {
+			return true
+		}
+	}
+	t.Errorf("expected a call of: %s\ncalls:\n%s", m, f.printableInvocations())
+	return false
+}
+
+// AssertNotCalled asserts that no command matching m was invoked.
+func (f *Factory) AssertNotCalled(t TestingT, m Matcher) bool {
+	t.Helper()
+
+	for _, invocation := range f.Invocations() {
+		if m.Match(invocation.Name, invocation.Args) {
+			t.Errorf("unexpected call of: %s (%s)", invocation, m)
+			return false
+		}
+	}
+	return true
+}
+
+// AssertCalledInOrder asserts that commands matching the matchers were invoked in the given order.
+// Other invocations may happen in between.
+func (f *Factory) AssertCalledInOrder(t TestingT, matchers ...Matcher) bool {
+	t.Helper()
+
+	next := 0
+	for _, invocation := range f.Invocations() {
+		if next < len(matchers) && matchers[next].Match(invocation.Name, invocation.Args) {
+			next++
+		}
+	}
+	if next == len(matchers) {
+		return true
+	}
+
+	t.Errorf("expected a call of: %s (call %d of %d in order)\ncalls:\n%s", matchers[next], next+1, len(matchers), f.printableInvocations())
+	return false
+}
+
+// record stores the invocation and returns the stub to be used for it, nil if no stub matches.
+func (f *Factory) record(invocation Invocation) *Stub {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.invocations = append(f.invocations, invocation)
+	for _, stub := range f.stubs {
+		if stub.available() && stub.matcher.Match(invocation.Name, invocation.Args) {
+			stub.calls++
+			return stub
+		}
+	}
+	return nil
+}
+
+func (f *Factory) printableInvocations() string {
+	var lines []string
+	for _, invocation := range f.Invocations() {
+		lines = append(lines, "  "+invocation.String())
+	}
+	if len(lines) == 0 {
+		return "  (none)"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// errNoStub is returned by the commands without a matching Stub.
+type errNoStub struct {
+	commandLine string
+}
+
+func (e errNoStub) Error() string {
+	return fmt.Sprintf("commandtest: no stub registered for: %s", e.commandLine)
+}
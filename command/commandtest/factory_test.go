@@ -0,0 +1,152 @@
+package commandtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a TestingT collecting the reported errors.
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestFactory(t *testing.T) {
+	factory := NewFactory()
+	factory.On(Exact("xcrun", "simctl", "list")).Stdout("iPhone 15\n")
+	factory.On(Glob("pod", "install", "--*")).Stderr("[!] network error\n").ExitCode(1).Times(1)
+	factory.On(Glob("pod", "install", "--*"))
+	factory.On(Regex(`^gradle .*assemble`)).StartError(errors.New("executable file not found in $PATH"))
+
+	var commandFactory command.Factory = factory
+
+	out, err := commandFactory.Create("xcrun", []string{"simctl", "list"}, &command.Opts{Dir: "/tmp", Env: []string{"A=B"}}).RunAndReturnTrimmedOutput()
+	require.NoError(t, err)
+	require.Equal(t, "iPhone 15", out)
+
+	var stderr bytes.Buffer
+	cmd := commandFactory.Create("pod", []string{"install", "--verbose"}, &command.Opts{Stderr: &stderr})
+	exitCode, err := cmd.RunAndReturnExitCode()
+	require.Equal(t, 1, exitCode)
+	var exitErr *command.ExitStatusError
+	require.True(t, errors.As(err, &exitErr))
+	require.Equal(t, 1, exitErr.ExitCode())
+	require.Equal(t, `command failed with exit status 1 (pod "install" "--verbose"): check the command's output for details`, err.Error())
+	require.Equal(t, "[!] network error\n", stderr.String())
+
+	require.NoError(t, commandFactory.Create("pod", []string{"install", "--verbose"}, nil).Run())
+
+	err = commandFactory.Create("gradle", []string{"app:assembleDebug"}, nil).Run()
+	require.EqualError(t, err, `executing command failed (gradle "app:assembleDebug"): executable file not found in $PATH`)
+
+	err = commandFactory.Create("unknown", nil, nil).Run()
+	require.EqualError(t, err, `executing command failed (unknown): commandtest: no stub registered for: unknown`)
+
+	require.Equal(t, []Invocation{
+		{Name: "xcrun", Args: []string{"simctl", "list"}, Env: []string{"A=B"}, Dir: "/tmp"},
+		{Name: "pod", Args: []string{"install", "--verbose"}},
+		{Name: "pod", Args: []string{"install", "--verbose"}},
+		{Name: "gradle", Args: []string{"app:assembleDebug"}},
+		{Name: "unknown"},
+	}, factory.Invocations())
+}
+
+func TestFactory_ErrorFinderAndStdin(t *testing.T) {
+	factory := NewFactory()
+	factory.On(Any()).Stdout("Error: first\nok\n").Stderr("Error: second\n").ExitCode(2)
+
+	errorFinder := func(out string) []string {
+		var lines []string
+		for _, line := range strings.Split(out, "\n") {
+			if strings.HasPrefix(line, "Error:") {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+	err := factory.Create("tool", nil, &command.Opts{ErrorFinder: errorFinder, Stdin: strings.NewReader("yes\n")}).Run()
+	require.EqualError(t, err, "command failed with exit status 2 (tool): Error: first\nError: second")
+	require.Equal(t, "yes\n", factory.Invocations()[0].Stdin)
+
+	_, err = factory.Create("tool", nil, nil).RunAndReturnTrimmedOutput()
+	require.EqualError(t, err, "command failed with exit status 2 (tool): Error: second\n")
+}
+
+func TestFactory_ErrorMatcherReusedAcrossRuns(t *testing.T) {
+	factory := NewFactory()
+	factory.On(Any()).Stderr("error: failed\n").ExitCode(1)
+
+	cmd := factory.Create("tool", nil, &command.Opts{ErrorMatcher: command.NewPrefixErrorMatcher(command.MatcherContext{})})
+	for i := 0; i < 2; i++ {
+		require.EqualError(t, cmd.Run(), "command failed with exit status 1 (tool): error: failed")
+	}
+}
+
+func TestFactory_Result(t *testing.T) {
+	factory := NewFactory()
+	factory.On(Any()).Stdout("out").Stderr("err")
+
+	result, err := factory.Create("tool", nil, nil).RunWithResult()
+	require.NoError(t, err)
+	require.Equal(t, "out", string(result.Stdout))
+	require.Equal(t, "err", string(result.Stderr))
+	require.Equal(t, "outerr", string(result.CombinedOutput))
+}
+
+func TestFactory_CancelledContext(t *testing.T) {
+	factory := NewFactory()
+	factory.On(Any())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := factory.CreateWithContext(ctx, "tool", nil, nil).Run()
+	require.ErrorIs(t, err, command.ErrCancelled)
+	require.Empty(t, factory.Invocations())
+}
+
+func TestFactory_StartWait(t *testing.T) {
+	factory := NewFactory()
+	factory.On(Any()).ExitCode(3)
+
+	cmd := factory.Create("tool", nil, nil)
+	require.Error(t, cmd.Wait())
+	require.NoError(t, cmd.Start())
+	require.NoError(t, cmd.Terminate())
+	var exitErr *command.ExitStatusError
+	require.True(t, errors.As(cmd.Wait(), &exitErr))
+}
+
+func TestFactory_Assertions(t *testing.T) {
+	factory := NewFactory()
+	factory.On(Any())
+	for _, name := range []string{"first", "second", "third"} {
+		require.NoError(t, factory.Create(name, []string{"arg"}, nil).Run())
+	}
+
+	r := &recorder{}
+	require.True(t, factory.AssertCalled(r, Exact("second", "arg")))
+	require.True(t, factory.AssertNotCalled(r, Exact("second")))
+	require.True(t, factory.AssertCalledInOrder(r, Exact("first", "arg"), Regex("^third")))
+	require.Empty(t, r.errors)
+
+	require.False(t, factory.AssertCalled(r, Exact("fourth")))
+	require.False(t, factory.AssertNotCalled(r, Glob("f*", "*")))
+	require.False(t, factory.AssertCalledInOrder(r, Exact("third", "arg"), Exact("first", "arg")))
+	require.Equal(t, []string{
+		"expected a call of: fourth\ncalls:\n  first arg\n  second arg\n  third arg",
+		"unexpected call of: first arg (glob: f* *)",
+		"expected a call of: first arg (call 2 of 2 in order)\ncalls:\n  first arg\n  second arg\n  third arg",
+	}, r.errors)
+}
@@ -0,0 +1,106 @@
+package commandtest
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matcher selects commands by their name and arguments.
+type Matcher interface {
+	Match(name string, args []string) bool
+	String() string
+}
+
+// Exact matches the commands with the given name and exactly the given arguments.
+func Exact(name string, args ...string) Matcher {
+	return exactMatcher{name: name, args: args}
+}
+
+// Glob matches the commands whose name and arguments match the given shell patterns (see filepath.Match) one by one.
+// The number of arguments needs to be the same.
+func Glob(name string, args ...string) Matcher {
+	return globMatcher{name: name, args: args}
+}
+
+// Regex matches the commands whose command line (the name and arguments joined by spaces) matches pattern.
+// It panics if pattern does not compile.
+func Regex(pattern string) Matcher {
+	return regexMatcher{re: regexp.MustCompile(pattern)}
+}
+
+// Any matches every command.
+func Any() Matcher {
+	return anyMatcher{}
+}
+
+type exactMatcher struct {
+	name string
+	args []string
+}
+
+func (m exactMatcher) Match(name string, args []string) bool {
+	if name != m.name || len(args) != len(m.args) {
+		return false
+	}
+	for i, arg := range args {
+		if arg != m.args[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m exactMatcher) String() string {
+	return commandLine(m.name, m.args)
+}
+
+type globMatcher struct {
+	name string
+	args []string
+}
+
+func (m globMatcher) Match(name string, args []string) bool {
+	if len(args) != len(m.args) {
+		return false
+	}
+	if ok, err := filepath.Match(m.name, name); err != nil || !ok {
+		return false
+	}
+	for i, arg := range args {
+		if ok, err := filepath.Match(m.args[i], arg); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (m globMatcher) String() string {
+	return "glob: " + commandLine(m.name, m.args)
+}
+
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexMatcher) Match(name string, args []string) bool {
+	return m.re.MatchString(commandLine(name, args))
+}
+
+func (m regexMatcher) String() string {
+	return "regex: " + m.re.String()
+}
+
+type anyMatcher struct{}
+
+func (anyMatcher) Match(string, []string) bool {
+	return true
+}
+
+func (anyMatcher) String() string {
+	return "any command"
+}
+
+func commandLine(name string, args []string) string {
+	return strings.Join(append([]string{name}, args...), " ")
+}
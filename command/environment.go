@@ -0,0 +1,89 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+)
+
+// EnvMode controls which environment variables of the Factory's env.Repository a command inherits.
+type EnvMode int
+
+const (
+	// EnvInherit passes every environment variable of the repository to the command (default).
+	EnvInherit EnvMode = iota
+	// EnvClean passes only Opts.Env to the command.
+	EnvClean
+	// EnvAllowList passes only the repository's variables listed in Opts.EnvKeys.
+	EnvAllowList
+	// EnvDenyList passes the repository's variables except the ones listed in Opts.EnvKeys.
+	EnvDenyList
+)
+
+// ResolveEnv returns the environment of a command created with opts by a Factory using envRepository:
+// the inherited variables (see EnvMode) merged with Opts.Env. Every key appears once, with its last value,
+// at the position of its first occurrence.
+func ResolveEnv(envRepository env.Repository, opts *Opts) []string {
+	if opts == nil {
+		return mergeEnv(envRepository.List())
+	}
+
+	var inherited []string
+	switch opts.EnvMode {
+	case EnvClean:
+	case EnvAllowList:
+		inherited = filterEnv(envRepository.List(), opts.EnvKeys, true)
+	case EnvDenyList:
+		inherited = filterEnv(envRepository.List(), opts.EnvKeys, false)
+	default:
+		inherited = envRepository.List()
+	}
+
+	return mergeEnv(inherited, opts.Env)
+}
+
+// filterEnv keeps the entries whose key is (keep == true) or is not (keep == false) in keys.
+func filterEnv(entries []string, keys []string, keep bool) []string {
+	keySet := map[string]bool{}
+	for _, key := range keys {
+		keySet[key] = true
+	}
+
+	var filtered []string
+	for _, entry := range entries {
+		if keySet[envKey(entry)] == keep {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// mergeEnv merges the "KEY=VALUE" lists, later values override the earlier ones.
+// The result is never nil, so an exec.Cmd does not fall back to the process environment.
+func mergeEnv(lists ...[]string) []string {
+	merged := []string{}
+	indexByKey := map[string]int{}
+	for _, list := range lists {
+		for _, entry := range list {
+			key := envKey(entry)
+			if idx, ok := indexByKey[key]; ok {
+				merged[idx] = entry
+				continue
+			}
+			indexByKey[key] = len(merged)
+			merged = append(merged, entry)
+		}
+	}
+	return merged
+}
+
+func envKey(entry string) string {
+	if entry == "" {
+		return ""
+	}
+	// On Windows some variables start with '=' (like "=C:=C:\\"), the key is never empty.
+	if idx := strings.IndexByte(entry[1:], '='); idx != -1 {
+		return entry[:idx+1]
+	}
+	return entry
+}
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+// listRepository is an env.Repository returning a fixed environment.
+type listRepository struct {
+	env.Repository
+	entries []string
+}
+
+func (r listRepository) List() []string {
+	return r.entries
+}
+
+func TestResolveEnv(t *testing.T) {
+	repository := listRepository{entries: []string{"PATH=/usr/bin", "HOME=/root", "TOKEN=secret", "HOME=/home/user"}}
+
+	tests := []struct {
+		name string
+		opts *Opts
+		want []string
+	}{
+		{
+			name: "no opts",
+			opts: nil,
+			want: []string{"PATH=/usr/bin", "HOME=/home/user", "TOKEN=secret"},
+		},
+		{
+			name: "inherit",
+			opts: &Opts{Env: []string{"PATH=/opt/bin", "NEW=1", "NEW=2"}},
+			want: []string{"PATH=/opt/bin", "HOME=/home/user", "TOKEN=secret", "NEW=2"},
+		},
+		{
+			name: "clean",
+			opts: &Opts{EnvMode: EnvClean, Env: []string{"NEW=1"}},
+			want: []string{"NEW=1"},
+		},
+		{
+			name: "clean without env",
+			opts: &Opts{EnvMode: EnvClean},
+			want: []string{},
+		},
+		{
+			name: "allow list",
+			opts: &Opts{EnvMode: EnvAllowList, EnvKeys: []string{"PATH", "MISSING"}, Env: []string{"NEW=1"}},
+			want: []string{"PATH=/usr/bin", "NEW=1"},
+		},
+		{
+			name: "deny list",
+			opts: &Opts{EnvMode: EnvDenyList, EnvKeys: []string{"TOKEN"}},
+			want: []string{"PATH=/usr/bin", "HOME=/home/user"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ResolveEnv(repository, tt.opts))
+		})
+	}
+}
+
+func Test_envKey(t *testing.T) {
+	require.Equal(t, "KEY", envKey("KEY=value=with=equal"))
+	require.Equal(t, "KEY", envKey("KEY="))
+	require.Equal(t, "=C:", envKey(`=C:=C:\`))
+	require.Equal(t, "NO_VALUE", envKey("NO_VALUE"))
+	require.Equal(t, "", envKey(""))
+}
+
+func TestEnvMode_Command(t *testing.T) {
+	t.Setenv("COMMAND_ENV_TEST", "inherited")
+	factory := NewFactory(env.NewRepository())
+
+	out, err := factory.Create("env", nil, &Opts{EnvMode: EnvClean, Env: []string{"ONLY=this"}}).RunAndReturnTrimmedOutput()
+	require.NoError(t, err)
+	require.Equal(t, "ONLY=this", out)
+
+	out, err = factory.Create("bash", []string{"-c", "echo ${COMMAND_ENV_TEST:-unset}"}, &Opts{EnvMode: EnvDenyList, EnvKeys: []string{"COMMAND_ENV_TEST"}}).RunAndReturnTrimmedOutput()
+	require.NoError(t, err)
+	require.Equal(t, "unset", out)
+}
+
+func TestFactoryWithLayeredRepository(t *testing.T) {
+	parent := env.NewMemoryRepository([]string{"PATH=" + os.Getenv("PATH"), "FROM_PARENT=parent"})
+	layer := env.NewLayeredRepository(parent)
+	require.NoError(t, layer.Set("FROM_LAYER", "layer"))
+	factory := NewFactory(layer)
+
+	out, err := factory.Create("env", nil, nil).RunAndReturnTrimmedOutput()
+	require.NoError(t, err)
+	require.Equal(t, "FROM_PARENT=parent\nPATH="+os.Getenv("PATH")+"\nFROM_LAYER=layer", out)
+}
@@ -1,11 +1,31 @@
 package command
 
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// maxLineLength caps the partial lines buffered by the line based writers: longer lines are split.
+const maxLineLength = 64 * 1024
+
 type errorCollector struct {
 	errorLines  []string
 	errorFinder ErrorFinder
+
+	// lineBuffered makes errorFinder receive complete lines only.
+	lineBuffered bool
+	matcher      ErrorMatcher
+
+	mux     sync.Mutex
+	streams []*lineWriter
 }
 
 func (e *errorCollector) Write(p []byte) (n int, err error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
 	e.collectErrors(string(p))
 	return len(p), nil
 }
@@ -16,3 +36,146 @@ func (e *errorCollector) collectErrors(output string) {
 		e.errorLines = append(e.errorLines, lines...)
 	}
 }
+
+// reset returns a new collector with the same configuration, for another run of the command.
+func (e *errorCollector) reset() *errorCollector {
+	if e.matcher != nil {
+		e.matcher.Reset()
+	}
+	return &errorCollector{
+		errorFinder:  e.errorFinder,
+		lineBuffered: e.lineBuffered,
+		matcher:      e.matcher,
+	}
+}
+
+// writer returns the io.Writer to be attached to an output stream of the command.
+// In line buffered mode every stream needs its own writer, to not mix partial lines of stdout and stderr.
+func (e *errorCollector) writer() io.Writer {
+	if !e.lineBuffered && e.matcher == nil {
+		return e
+	}
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	w := &lineWriter{onLines: e.collectLines}
+	e.streams = append(e.streams, w)
+	return w
+}
+
+// collectOutput processes the whole output of a command at once.
+func (e *errorCollector) collectOutput(output string) {
+	if e.errorFinder != nil {
+		e.collectErrors(output)
+	}
+	if e.matcher != nil {
+		for _, line := range splitLines(output) {
+			e.matcher.Feed(line)
+		}
+		e.matcher.Flush()
+	}
+}
+
+func (e *errorCollector) collectLines(lines []string) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if e.errorFinder != nil {
+		e.collectErrors(strings.Join(lines, "\n") + "\n")
+	}
+	if e.matcher != nil {
+		for _, line := range lines {
+			e.matcher.Feed(line)
+		}
+	}
+}
+
+// flush processes the remaining partial lines, to be called once the command exited.
+func (e *errorCollector) flush() {
+	e.mux.Lock()
+	streams := e.streams
+	e.mux.Unlock()
+
+	for _, stream := range streams {
+		stream.flush()
+	}
+
+	if e.matcher != nil {
+		e.mux.Lock()
+		e.matcher.Flush()
+		e.mux.Unlock()
+	}
+}
+
+// errors returns the lines found by the errorFinder followed by the matches of the matcher.
+func (e *errorCollector) errors() []string {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	lines := append([]string{}, e.errorLines...)
+	if e.matcher != nil {
+		for _, match := range e.matcher.Matches() {
+			lines = append(lines, match.String())
+		}
+	}
+	return lines
+}
+
+// lineWriter is an io.Writer which passes the complete lines written to it to onLines.
+// Lines longer than maxLineLength are passed in maxLineLength long parts.
+type lineWriter struct {
+	onLines func(lines []string)
+
+	mux     sync.Mutex
+	partial []byte
+}
+
+// Write implements io.Writer.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	data := append(w.partial, p...)
+	var lines []string
+	if idx := bytes.LastIndexByte(data, '\n'); idx != -1 {
+		lines = splitLines(string(data[:idx+1]))
+		data = data[idx+1:]
+	}
+	for len(data) >= maxLineLength {
+		lines = append(lines, string(data[:maxLineLength]))
+		data = data[maxLineLength:]
+	}
+	w.partial = append([]byte(nil), data...)
+
+	if len(lines) > 0 {
+		w.onLines(lines)
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if len(w.partial) == 0 {
+		return
+	}
+	line := string(w.partial)
+	w.partial = nil
+	w.onLines([]string{strings.TrimSuffix(line, "\r")})
+}
+
+// splitLines splits the output into lines, without line endings.
+func splitLines(output string) []string {
+	output = strings.TrimSuffix(output, "\n")
+	if output == "" {
+		return nil
+	}
+
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
@@ -0,0 +1,95 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+func errorPrefixFinder(out string) []string {
+	var errors []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "Error:") {
+			errors = append(errors, line)
+		}
+	}
+	return errors
+}
+
+func TestErrorCollector_LineBuffered(t *testing.T) {
+	tests := []struct {
+		name         string
+		lineBuffered bool
+		want         []string
+	}{
+		{
+			name:         "chunks",
+			lineBuffered: false,
+			want:         []string{"Error: fir", "Error: third"},
+		},
+		{
+			name:         "line buffered",
+			lineBuffered: true,
+			want:         []string{"Error: first", "Error: second", "Error: third"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &errorCollector{errorFinder: errorPrefixFinder, lineBuffered: tt.lineBuffered}
+			w := collector.writer()
+			for _, chunk := range []string{"Error: fir", "st\nErr", "or: second\r\nok\nError: third"} {
+				_, err := w.Write([]byte(chunk))
+				require.NoError(t, err)
+			}
+			collector.flush()
+
+			require.Equal(t, tt.want, collector.errors())
+		})
+	}
+}
+
+func TestErrorCollector_StreamsDoNotMix(t *testing.T) {
+	collector := &errorCollector{matcher: NewPrefixErrorMatcher(MatcherContext{})}
+	stdout, stderr := collector.writer(), collector.writer()
+
+	_, err := stdout.Write([]byte("error: from "))
+	require.NoError(t, err)
+	_, err = stderr.Write([]byte("error: from stderr\n"))
+	require.NoError(t, err)
+	_, err = stdout.Write([]byte("stdout\n"))
+	require.NoError(t, err)
+	collector.flush()
+
+	require.Equal(t, []string{"error: from stderr", "error: from stdout"}, collector.errors())
+}
+
+func TestLineWriter_LongLine(t *testing.T) {
+	var got []string
+	w := &lineWriter{onLines: func(lines []string) { got = append(got, lines...) }}
+
+	chunk := strings.Repeat("x", 1000)
+	for i := 0; i < 100; i++ {
+		_, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	require.Len(t, w.partial, 100*1000-maxLineLength)
+	_, err := w.Write([]byte("\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{strings.Repeat("x", maxLineLength), strings.Repeat("x", 100*1000-maxLineLength)}, got)
+}
+
+func TestErrorMatcherOpt(t *testing.T) {
+	cmd := NewFactory(env.NewRepository()).Create("bash", []string{"testdata/exit_with_message.sh"}, &Opts{
+		ErrorMatcher: NewPrefixErrorMatcher(MatcherContext{After: 1}),
+	})
+
+	out, err := cmd.RunAndReturnTrimmedOutput()
+	require.Contains(t, out, "This is not an stdout error")
+	require.EqualError(t, err, `command failed with exit status 1 (bash "testdata/exit_with_message.sh"): Error: first error
+Error: second error
+Error: second error
+This is not an stdout error`)
+}
@@ -0,0 +1,226 @@
+package command
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ErrorMatch is an error found in the output of a command.
+type ErrorMatch struct {
+	// Lines of the error, a multiline error consists of more than one line.
+	Lines []string
+	// Before holds the context lines preceding the error.
+	Before []string
+	// After holds the context lines following the error.
+	After []string
+}
+
+// String returns the error lines together with the context lines.
+func (m ErrorMatch) String() string {
+	var lines []string
+	lines = append(lines, m.Before...)
+	lines = append(lines, m.Lines...)
+	lines = append(lines, m.After...)
+	return strings.Join(lines, "\n")
+}
+
+// ErrorMatcher is a stateful matcher fed with the output of a command line by line.
+// Implementations do not need to be safe for concurrent use, the command serializes the calls.
+type ErrorMatcher interface {
+	// Feed processes the next line of the output, without the line ending.
+	Feed(line string)
+	// Flush is called at the end of the output, to finish the pending matches.
+	Flush()
+	// Matches returns the errors found so far.
+	Matches() []ErrorMatch
+	// Reset drops the state, called before the command runs again.
+	Reset()
+}
+
+// MatcherContext is the number of context lines attached to the matches of the built-in ErrorMatchers.
+type MatcherContext struct {
+	Before int
+	After  int
+}
+
+// NewRegexErrorMatcher returns an ErrorMatcher which matches the lines matching any of the patterns.
+func NewRegexErrorMatcher(context MatcherContext, patterns ...*regexp.Regexp) ErrorMatcher {
+	var rules []matchRule
+	for _, pattern := range patterns {
+		rules = append(rules, matchRule{start: pattern.MatchString})
+	}
+	return newLineMatcher(context, rules...)
+}
+
+// NewPrefixErrorMatcher returns an ErrorMatcher which matches the lines starting with "error:" (case-insensitive).
+func NewPrefixErrorMatcher(context MatcherContext) ErrorMatcher {
+	return newLineMatcher(context, matchRule{start: func(line string) bool {
+		return strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), "error:")
+	}})
+}
+
+var (
+	xcodebuildErrorPattern  = regexp.MustCompile(`(^|[\s:])error: `)
+	xcodebuildFailedPattern = regexp.MustCompile(`^\*\* [A-Z ]+ FAILED \*\*`)
+)
+
+// NewXcodebuildErrorMatcher returns an ErrorMatcher for xcodebuild output.
+// It matches compiler and xcodebuild errors, the "** BUILD FAILED **" like summaries
+// and the "The following build commands failed:" and "Testing failed:" blocks.
+func NewXcodebuildErrorMatcher(context MatcherContext) ErrorMatcher {
+	indented := func(line string) bool {
+		return strings.HasPrefix(line, "\t") || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "(")
+	}
+
+	return newLineMatcher(context,
+		matchRule{start: equals("The following build commands failed:"), next: indented},
+		matchRule{start: equals("Testing failed:"), next: indented},
+		matchRule{start: xcodebuildErrorPattern.MatchString},
+		matchRule{start: xcodebuildFailedPattern.MatchString},
+	)
+}
+
+var (
+	kotlinErrorPattern = regexp.MustCompile(`^e: `)
+	javacErrorPattern  = regexp.MustCompile(`^\S.*:\d+: error: `)
+)
+
+// NewGradleErrorMatcher returns an ErrorMatcher for Gradle output.
+// It matches the "* What went wrong:" block of the build failure report, the "FAILURE:" summary
+// and the javac and kotlinc compiler errors.
+func NewGradleErrorMatcher(context MatcherContext) ErrorMatcher {
+	return newLineMatcher(context,
+		matchRule{start: equals("* What went wrong:"), next: func(line string) bool {
+			return !strings.HasPrefix(line, "* ")
+		}},
+		matchRule{start: func(line string) bool { return strings.HasPrefix(line, "FAILURE: ") }},
+		matchRule{start: kotlinErrorPattern.MatchString},
+		matchRule{start: javacErrorPattern.MatchString},
+	)
+}
+
+func equals(s string) func(string) bool {
+	return func(line string) bool {
+		return strings.TrimSpace(line) == s
+	}
+}
+
+// matchRule describes an error in the output.
+type matchRule struct {
+	// start reports whether the line is (the first line of) an error.
+	start func(line string) bool
+	// next reports whether the line continues a multiline error, nil for single line errors.
+	next func(line string) bool
+}
+
+type pendingMatch struct {
+	ErrorMatch
+	afterLeft int
+}
+
+// lineMatcher implements ErrorMatcher by applying rules on the lines and collecting the context lines.
+type lineMatcher struct {
+	context MatcherContext
+	rules   []matchRule
+
+	history     []string
+	matches     []*pendingMatch
+	current     *pendingMatch
+	currentRule matchRule
+}
+
+func newLineMatcher(context MatcherContext, rules ...matchRule) *lineMatcher {
+	return &lineMatcher{
+		context: context,
+		rules:   rules,
+	}
+}
+
+// Feed ...
+func (m *lineMatcher) Feed(line string) {
+	continued := false
+	if m.current != nil {
+		if m.currentRule.next(line) {
+			m.current.Lines = append(m.current.Lines, line)
+			continued = true
+		} else {
+			m.finishCurrent()
+		}
+	}
+
+	for _, match := range m.matches {
+		if match.afterLeft > 0 {
+			match.After = append(match.After, line)
+			match.afterLeft--
+		}
+	}
+
+	if !continued {
+		for _, rule := range m.rules {
+			if !rule.start(line) {
+				continue
+			}
+
+			match := &pendingMatch{ErrorMatch: ErrorMatch{
+				Lines:  []string{line},
+				Before: append([]string(nil), m.history...),
+			}}
+			m.matches = append(m.matches, match)
+			if rule.next != nil {
+				m.current = match
+				m.currentRule = rule
+			} else {
+				match.afterLeft = m.context.After
+			}
+			break
+		}
+	}
+
+	if m.context.Before > 0 {
+		m.history = append(m.history, line)
+		if len(m.history) > m.context.Before {
+			m.history = m.history[len(m.history)-m.context.Before:]
+		}
+	}
+}
+
+// Flush ...
+func (m *lineMatcher) Flush() {
+	if m.current != nil {
+		m.finishCurrent()
+	}
+	for _, match := range m.matches {
+		match.afterLeft = 0
+	}
+}
+
+// Matches ...
+func (m *lineMatcher) Matches() []ErrorMatch {
+	var matches []ErrorMatch
+	for _, match := range m.matches {
+		matches = append(matches, ErrorMatch{
+			Lines:  append([]string(nil), match.Lines...),
+			Before: append([]string(nil), match.Before...),
+			After:  append([]string(nil), match.After...),
+		})
+	}
+	return matches
+}
+
+// Reset ...
+func (m *lineMatcher) Reset() {
+	m.history = nil
+	m.matches = nil
+	m.current = nil
+	m.currentRule = matchRule{}
+}
+
+func (m *lineMatcher) finishCurrent() {
+	lines := m.current.Lines
+	for len(lines) > 1 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	m.current.Lines = lines
+	m.current.afterLeft = m.context.After
+	m.current = nil
+}
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func feed(m ErrorMatcher, output string) []ErrorMatch {
+	for _, line := range strings.Split(output, "\n") {
+		m.Feed(line)
+	}
+	m.Flush()
+	return m.Matches()
+}
+
+func TestRegexErrorMatcher(t *testing.T) {
+	m := NewRegexErrorMatcher(MatcherContext{Before: 1, After: 2}, regexp.MustCompile(`^\[!\]`), regexp.MustCompile(`failed$`))
+
+	got := feed(m, `Analyzing dependencies
+[!] CocoaPods could not find compatible versions
+  In Podfile:
+    Alamofire
+Installing pods
+download failed`)
+
+	require.Equal(t, []ErrorMatch{
+		{
+			Before: []string{"Analyzing dependencies"},
+			Lines:  []string{"[!] CocoaPods could not find compatible versions"},
+			After:  []string{"  In Podfile:", "    Alamofire"},
+		},
+		{
+			Before: []string{"Installing pods"},
+			Lines:  []string{"download failed"},
+		},
+	}, got)
+	require.Equal(t, "Analyzing dependencies\n[!] CocoaPods could not find compatible versions\n  In Podfile:\n    Alamofire", got[0].String())
+}
+
+func TestPrefixErrorMatcher(t *testing.T) {
+	got := feed(NewPrefixErrorMatcher(MatcherContext{}), "ok\n  Error: something went wrong\nan error: not at the start")
+
+	require.Equal(t, []ErrorMatch{{Lines: []string{"  Error: something went wrong"}}}, got)
+}
+
+func TestXcodebuildErrorMatcher(t *testing.T) {
+	output := `CompileSwift normal arm64 /src/App/View.swift
+/src/App/View.swift:12:5: error: cannot find 'foo' in scope
+/src/App/View.swift:13:5: warning: variable 'x' was never used
+
+** BUILD FAILED **
+
+
+The following build commands failed:
+	CompileSwift normal arm64 /src/App/View.swift (in target 'App' from project 'App')
+	SwiftCompile normal arm64 Compiling\ View.swift (in target 'App' from project 'App')
+(2 failures)
+xcodebuild: error: Failed to build workspace App with scheme App.`
+
+	var got []string
+	for _, match := range feed(NewXcodebuildErrorMatcher(MatcherContext{}), output) {
+		got = append(got, match.String())
+	}
+
+	require.Equal(t, []string{
+		"/src/App/View.swift:12:5: error: cannot find 'foo' in scope",
+		"** BUILD FAILED **",
+		`The following build commands failed:
+	CompileSwift normal arm64 /src/App/View.swift (in target 'App' from project 'App')
+	SwiftCompile normal arm64 Compiling\ View.swift (in target 'App' from project 'App')
+(2 failures)`,
+		"xcodebuild: error: Failed to build workspace App with scheme App.",
+	}, got)
+}
+
+func TestGradleErrorMatcher(t *testing.T) {
+	output := `> Task :app:compileDebugKotlin FAILED
+e: /src/app/Main.kt: (10, 5): Unresolved reference: foo
+
+FAILURE: Build failed with an exception.
+
+* What went wrong:
+Execution failed for task ':app:compileDebugKotlin'.
+> Compilation error. See log for more details
+
+* Try:
+> Run with --stacktrace option to get the stack trace.`
+
+	var got []string
+	for _, match := range feed(NewGradleErrorMatcher(MatcherContext{}), output) {
+		got = append(got, match.String())
+	}
+
+	require.Equal(t, []string{
+		"e: /src/app/Main.kt: (10, 5): Unresolved reference: foo",
+		"FAILURE: Build failed with an exception.",
+		`* What went wrong:
+Execution failed for task ':app:compileDebugKotlin'.
+> Compilation error. See log for more details`,
+	}, got)
+}
+
+func TestLineMatcher_MultilineFlushedAtEnd(t *testing.T) {
+	got := feed(NewGradleErrorMatcher(MatcherContext{After: 1}), "* What went wrong:\nCould not resolve all files.\n")
+
+	require.Equal(t, []ErrorMatch{{Lines: []string{"* What went wrong:", "Could not resolve all files."}}}, got)
+}
@@ -5,22 +5,51 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
+)
+
+var (
+	// ErrCancelled is returned when a command was stopped because its context was cancelled.
+	ErrCancelled = errors.New("command cancelled")
+	// ErrTimedOut is returned when a command was stopped because its context deadline was exceeded.
+	ErrTimedOut = errors.New("command timed out")
+	// ErrTerminated is returned when a command was stopped by Command.Terminate or Command.Kill.
+	ErrTerminated = errors.New("command terminated")
 )
 
 // ExitStatusError ...
 type ExitStatusError struct {
 	readableReason  error
 	originalExitErr error
+	exitCode        int
+	errorLines      []string
+	logFile         string
+	pipelineStage   int
 }
 
 // NewExitStatusError ...
+// A command killed by a signal is reported with the signal instead of its exit status (-1).
 func NewExitStatusError(printableCmdArgs string, exitErr *exec.ExitError, errorLines []string) error {
-	reasonMsg := fmt.Sprintf("command failed with exit status %d (%s)", exitErr.ExitCode(), printableCmdArgs)
+	status := fmt.Sprintf("exit status %d", exitErr.ExitCode())
+	if sig := exitSignal(exitErr.ProcessState); sig != nil {
+		status = "signal: " + sig.String()
+	}
+	return newExitStatusError(printableCmdArgs, status, exitErr.ExitCode(), exitErr, string(exitErr.Stderr), errorLines)
+}
+
+// NewExitStatusErrorFromCode returns an ExitStatusError for a command which exited with exitCode,
+// without an underlying exec.ExitError. Useful for Command implementations not backed by a process, like fakes in tests.
+func NewExitStatusErrorFromCode(printableCmdArgs string, exitCode int, errorLines []string) error {
+	return newExitStatusError(printableCmdArgs, fmt.Sprintf("exit status %d", exitCode), exitCode, nil, "", errorLines)
+}
+
+func newExitStatusError(printableCmdArgs, status string, exitCode int, exitErr error, stderr string, errorLines []string) error {
+	reasonMsg := fmt.Sprintf("command failed with %s (%s)", status, printableCmdArgs)
 
 	errorOutput := strings.Join(errorLines, "\n")
 	if len(errorOutput) == 0 {
-		if len(exitErr.Stderr) != 0 {
-			errorOutput = string(exitErr.Stderr)
+		if len(stderr) != 0 {
+			errorOutput = stderr
 		} else {
 			errorOutput = "check the command's output for details"
 		}
@@ -29,9 +58,44 @@ func NewExitStatusError(printableCmdArgs string, exitErr *exec.ExitError, errorL
 	return &ExitStatusError{
 		readableReason:  fmt.Errorf("%s: %w", reasonMsg, errors.New(errorOutput)),
 		originalExitErr: exitErr,
+		exitCode:        exitCode,
+		errorLines:      errorLines,
 	}
 }
 
+// ExitCode returns the exit code of the command.
+func (e *ExitStatusError) ExitCode() int {
+	return e.exitCode
+}
+
+// ErrorLines returns the lines found in the command's output by Opts.ErrorFinder and Opts.ErrorMatcher.
+func (e *ExitStatusError) ErrorLines() []string {
+	return e.errorLines
+}
+
+// LogFile returns the path of the file the full output of the command was written to (see Opts.LogFile),
+// empty if the output was not logged.
+func (e *ExitStatusError) LogFile() string {
+	return e.logFile
+}
+
+// atPipelineStage returns a copy of the error, reporting that the given (1-based) stage of the pipeline failed.
+func (e *ExitStatusError) atPipelineStage(stage int, printablePipeline string) *ExitStatusError {
+	return &ExitStatusError{
+		readableReason:  fmt.Errorf("pipeline stage %d failed (%s): %w", stage, printablePipeline, e.readableReason),
+		originalExitErr: e.originalExitErr,
+		exitCode:        e.exitCode,
+		errorLines:      e.errorLines,
+		logFile:         e.logFile,
+		pipelineStage:   stage,
+	}
+}
+
+// PipelineStage returns the 1-based index of the failed stage if the error was returned by a Pipeline, 0 otherwise.
+func (e *ExitStatusError) PipelineStage() int {
+	return e.pipelineStage
+}
+
 // Error returns the formatted error message. Does not include the original error message (`exit status 1`).
 func (e *ExitStatusError) Error() string {
 	return e.readableReason.Error()
@@ -46,3 +110,22 @@ func (e *ExitStatusError) Unwrap() error {
 func (e *ExitStatusError) Reason() error {
 	return e.readableReason
 }
+
+// HangError is returned when a command was stopped because it did not produce any output for Opts.NoOutputTimeout.
+type HangError struct {
+	// Timeout is the inactivity period after the command was stopped.
+	Timeout time.Duration
+	// LastLines are the last lines of the command's output (stdout and stderr) before it was stopped.
+	LastLines []string
+
+	printableCmdArgs string
+}
+
+// Error returns the reason of stopping the command followed by its last output lines.
+func (e *HangError) Error() string {
+	msg := fmt.Sprintf("command produced no output for %s (%s)", e.Timeout, e.printableCmdArgs)
+	if len(e.LastLines) == 0 {
+		return msg
+	}
+	return msg + ", last output:\n" + strings.Join(e.LastLines, "\n")
+}
@@ -0,0 +1,234 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/go-utils/v2/redactwriter"
+)
+
+const (
+	// DefaultExpectTimeout is the time an ExpectStep waits for its pattern by default.
+	DefaultExpectTimeout = time.Minute
+
+	// expectOutputLimit is the number of output bytes retained for matching the pattern of the current step.
+	expectOutputLimit = 64 * 1024
+)
+
+// Expect answers the prompts of an interactive command, see Opts.Expect.
+type Expect struct {
+	// Steps are processed in order: each waits for its Pattern and writes its Response to the command's stdin.
+	// Stdin is closed after the last step. Steps not reached before the command exits are ignored.
+	Steps []ExpectStep
+	// Transcript receives the output of the command and the responses written to its stdin,
+	// with Opts.Secrets redacted.
+	Transcript io.Writer
+	// Logger reports the errors of writing the Transcript, by default they are discarded.
+	Logger log.Logger
+}
+
+// ExpectStep waits for a prompt of the command and answers it.
+type ExpectStep struct {
+	// Pattern is matched against the output (stdout and stderr) written since the previous step matched.
+	Pattern *regexp.Regexp
+	// Response is written to the command's stdin when Pattern matched, include the line ending if needed.
+	Response string
+	// Timeout stops the command with an *ExpectError if Pattern does not match in time.
+	// Defaults to DefaultExpectTimeout.
+	Timeout time.Duration
+}
+
+// ExpectError is returned when the command did not print the output expected by an ExpectStep in time.
+type ExpectError struct {
+	// Step is the index of the step in Expect.Steps.
+	Step int
+	// Pattern of the step.
+	Pattern *regexp.Regexp
+	// Timeout of the step.
+	Timeout time.Duration
+	// Output is the output written since the previous step matched (its last 64 KiB).
+	Output string
+
+	printableCmdArgs string
+}
+
+// Error ...
+func (e *ExpectError) Error() string {
+	return fmt.Sprintf("command did not print output matching %q within %s (%s)", e.Pattern, e.Timeout, e.printableCmdArgs)
+}
+
+// expectSession runs the steps of an Expect for a started command.
+type expectSession struct {
+	expect     Expect
+	transcript *redactwriter.Writer
+
+	stdinReader *os.File
+	stdinWriter *os.File
+
+	mux     sync.Mutex
+	output  []byte
+	stopped bool
+	notify  chan struct{}
+	done    chan struct{}
+}
+
+func newExpectSession(expect Expect, secrets []string) *expectSession {
+	s := &expectSession{
+		expect: expect,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	if expect.Transcript != nil {
+		logger := expect.Logger
+		if logger == nil {
+			logger = log.NewLogger(log.WithOutput(io.Discard))
+		}
+		s.transcript = redactwriter.New(secrets, expect.Transcript, logger)
+	}
+	return s
+}
+
+// Write implements io.Writer, it receives the output of the command.
+func (s *expectSession) Write(p []byte) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.stopped {
+		return len(p), nil
+	}
+
+	s.output = append(s.output, p...)
+	if len(s.output) > expectOutputLimit {
+		s.output = s.output[len(s.output)-expectOutputLimit:]
+	}
+	if s.transcript != nil {
+		_, _ = s.transcript.Write(p)
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	return len(p), nil
+}
+
+// open creates the stdin of the command, which is written by the session.
+func (s *expectSession) open(stdin io.Reader) (*os.File, error) {
+	if stdin != nil {
+		return nil, errors.New("expect: Stdin already set")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	s.stdinReader, s.stdinWriter = r, w
+	return r, nil
+}
+
+// run processes the steps, onTimeout is called if a step times out.
+func (s *expectSession) run(onTimeout func(*ExpectError)) {
+	defer func() {
+		_ = s.stdinWriter.Close()
+	}()
+
+	for i, step := range s.expect.Steps {
+		timeout := step.Timeout
+		if timeout <= 0 {
+			timeout = DefaultExpectTimeout
+		}
+		if !s.waitFor(step.Pattern, timeout) {
+			select {
+			case <-s.done:
+			default:
+				onTimeout(&ExpectError{
+					Step:    i,
+					Pattern: step.Pattern,
+					Timeout: timeout,
+					Output:  s.pendingOutput(),
+				})
+			}
+			return
+		}
+
+		if !s.respond(step.Response) {
+			return
+		}
+	}
+}
+
+// waitFor returns true when pattern matched the output, false on timeout or when the session stopped.
+func (s *expectSession) waitFor(pattern *regexp.Regexp, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.mux.Lock()
+		loc := pattern.FindIndex(s.output)
+		if loc != nil {
+			s.output = s.output[loc[1]:]
+		}
+		s.mux.Unlock()
+
+		if loc != nil {
+			return true
+		}
+
+		select {
+		case <-s.notify:
+		case <-timer.C:
+			return false
+		case <-s.done:
+			return false
+		}
+	}
+}
+
+func (s *expectSession) respond(response string) bool {
+	s.mux.Lock()
+	if s.stopped {
+		s.mux.Unlock()
+		return false
+	}
+	if s.transcript != nil {
+		_, _ = s.transcript.Write([]byte(response))
+	}
+	s.mux.Unlock()
+
+	_, err := io.WriteString(s.stdinWriter, response)
+	return err == nil
+}
+
+func (s *expectSession) pendingOutput() string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return string(s.output)
+}
+
+// stop ends the session after the command exited, and flushes the transcript.
+func (s *expectSession) stop() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	close(s.done)
+
+	if s.stdinReader != nil {
+		_ = s.stdinReader.Close()
+		_ = s.stdinWriter.Close()
+	}
+	if s.transcript != nil {
+		_ = s.transcript.Close()
+	}
+}
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+const promptScript = `printf "Name: "; read name; printf "Password: " >&2; read password; echo "hello $name ($password)"`
+
+func TestExpect(t *testing.T) {
+	factory := NewFactory(env.NewRepository())
+
+	t.Run("answers prompts", func(t *testing.T) {
+		var stdout, transcript bytes.Buffer
+		cmd := factory.Create("bash", []string{"-c", promptScript}, &Opts{
+			Stdout:  &stdout,
+			Secrets: []string{"s3cret"},
+			Expect: &Expect{
+				Steps: []ExpectStep{
+					{Pattern: regexp.MustCompile(`Name: $`), Response: "Bitrise\n"},
+					{Pattern: regexp.MustCompile(`Password: $`), Response: "s3cret\n"},
+				},
+				Transcript: &transcript,
+			},
+		})
+
+		require.NoError(t, cmd.Run())
+		require.Equal(t, "Name: hello Bitrise (s3cret)\n", stdout.String())
+		require.Equal(t, "Name: Bitrise\nPassword: [REDACTED]\nhello Bitrise ([REDACTED])\n", transcript.String())
+	})
+
+	t.Run("step timeout", func(t *testing.T) {
+		cmd := factory.Create("bash", []string{"-c", "printf 'Continue? '; sleep 10"}, &Opts{
+			Expect: &Expect{Steps: []ExpectStep{
+				{Pattern: regexp.MustCompile(`Accept\?`), Response: "y\n", Timeout: 200 * time.Millisecond},
+			}},
+		})
+
+		start := time.Now()
+		err := cmd.Run()
+		require.Less(t, time.Since(start), 5*time.Second)
+
+		var expectErr *ExpectError
+		require.ErrorAs(t, err, &expectErr)
+		require.Equal(t, 0, expectErr.Step)
+		require.Equal(t, "Continue? ", expectErr.Output)
+		require.EqualError(t, err, `command did not print output matching "Accept\\?" within 200ms (bash "-c" "printf 'Continue? '; sleep 10")`)
+	})
+
+	t.Run("steps not reached", func(t *testing.T) {
+		cmd := factory.Create("bash", []string{"-c", "echo done"}, &Opts{
+			Expect: &Expect{Steps: []ExpectStep{
+				{Pattern: regexp.MustCompile(`Accept\?`), Response: "y\n"},
+			}},
+		})
+
+		out, err := cmd.RunAndReturnTrimmedOutput()
+		require.NoError(t, err)
+		require.Equal(t, "done", out)
+	})
+
+	t.Run("stdin already set", func(t *testing.T) {
+		cmd := factory.Create("cat", nil, &Opts{
+			Stdin:  strings.NewReader("input"),
+			Expect: &Expect{},
+		})
+
+		require.EqualError(t, cmd.Run(), "executing command failed (cat): expect: Stdin already set")
+	})
+}
@@ -0,0 +1,233 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// GroupOpts configures a Group.
+type GroupOpts struct {
+	// Concurrency is the maximum number of commands running at the same time. Defaults to runtime.NumCPU().
+	Concurrency int
+	// FailFast terminates the running commands and skips the pending ones after the first failure.
+	// By default every command runs and all of the failures are collected.
+	FailFast bool
+	// Output receives the output lines of the commands, prefixed with the name of the command ("[name] line"),
+	// besides the writers configured in the commands' Opts.
+	// Only the output of commands created by a Factory of this package and of Pipelines can be prefixed.
+	Output io.Writer
+}
+
+// Group runs independent commands concurrently.
+type Group struct {
+	opts    GroupOpts
+	entries []groupEntry
+}
+
+type groupEntry struct {
+	name string
+	cmd  Command
+}
+
+// NewGroup ...
+func NewGroup(opts GroupOpts) *Group {
+	return &Group{opts: opts}
+}
+
+// Add adds cmd to the group, name identifies it in the output and in the errors.
+func (g *Group) Add(name string, cmd Command) {
+	g.entries = append(g.entries, groupEntry{name: name, cmd: cmd})
+}
+
+// Run runs the commands of the group and waits for them to finish.
+// It returns a *GroupError if any of the commands failed.
+func (g *Group) Run() error {
+	concurrency := g.opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	run := &groupRun{
+		group:     g,
+		errs:      make([]error, len(g.entries)),
+		cancelled: make([]bool, len(g.entries)),
+		running:   map[int]Command{},
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, entry := range g.entries {
+		sem <- struct{}{}
+		if run.isFailed() && g.opts.FailFast {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, entry groupEntry) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			run.runEntry(i, entry)
+		}(i, entry)
+	}
+	wg.Wait()
+
+	return run.err()
+}
+
+// groupRun is the state of a Group.Run.
+type groupRun struct {
+	group *Group
+
+	outputMux sync.Mutex
+
+	mux       sync.Mutex
+	failed    bool
+	errs      []error
+	cancelled []bool
+	running   map[int]Command
+}
+
+func (r *groupRun) isFailed() bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.failed
+}
+
+func (r *groupRun) runEntry(i int, entry groupEntry) {
+	var streams []*lineWriter
+	if r.group.opts.Output != nil {
+		stdout, stderr := r.prefixWriter(entry.name), r.prefixWriter(entry.name)
+		if setOutputTees(entry.cmd, stdout, stderr) {
+			streams = append(streams, stdout, stderr)
+		}
+	}
+
+	err := entry.cmd.Start()
+	if err == nil {
+		if !r.track(i, entry.cmd) {
+			_ = entry.cmd.Terminate()
+		}
+		err = entry.cmd.Wait()
+	}
+
+	for _, stream := range streams {
+		stream.flush()
+	}
+	if len(streams) > 0 {
+		setOutputTees(entry.cmd, nil, nil)
+	}
+	r.finish(i, entry.name, err)
+}
+
+// track registers a started command, returns false if the group already failed in fail-fast mode.
+func (r *groupRun) track(i int, cmd Command) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.failed && r.group.opts.FailFast {
+		r.cancelled[i] = true
+		return false
+	}
+	r.running[i] = cmd
+	return true
+}
+
+func (r *groupRun) finish(i int, name string, err error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	delete(r.running, i)
+	// Commands terminated by the group are not reported as failures.
+	if err == nil || r.cancelled[i] {
+		return
+	}
+	r.errs[i] = fmt.Errorf("%s: %w", name, err)
+
+	if !r.failed && r.group.opts.FailFast {
+		for j, cmd := range r.running {
+			r.cancelled[j] = true
+			_ = cmd.Terminate()
+		}
+	}
+	r.failed = true
+}
+
+func (r *groupRun) err() error {
+	var errs []error
+	for _, err := range r.errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &GroupError{Errors: errs, Total: len(r.errs)}
+}
+
+// prefixWriter writes the complete lines written to it to the group's output, prefixed with name.
+func (r *groupRun) prefixWriter(name string) *lineWriter {
+	prefix := "[" + name + "] "
+	return &lineWriter{onLines: func(lines []string) {
+		var b strings.Builder
+		for _, line := range lines {
+			b.WriteString(prefix)
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+
+		r.outputMux.Lock()
+		defer r.outputMux.Unlock()
+		_, _ = io.WriteString(r.group.opts.Output, b.String())
+	}}
+}
+
+// setOutputTees makes the outputs of cmd written to stdout and stderr too,
+// returns false if the Command implementation does not support it.
+func setOutputTees(cmd Command, stdout, stderr io.Writer) bool {
+	switch cmd := cmd.(type) {
+	case *command:
+		cmd.stdoutTee = stdout
+		cmd.stderrTee = stderr
+		return true
+	case *pipeline:
+		if len(cmd.stages) == 0 {
+			return false
+		}
+		for _, stage := range cmd.stages {
+			stage.stderrTee = stderr
+		}
+		cmd.stages[len(cmd.stages)-1].stdoutTee = stdout
+		return true
+	default:
+		return false
+	}
+}
+
+// GroupError is returned by Group.Run when some of its commands failed.
+type GroupError struct {
+	// Errors of the failed commands in the order the commands were added to the group,
+	// prefixed with the name of the command.
+	Errors []error
+	// Total is the number of commands in the group.
+	Total int
+}
+
+// Error returns the number of failed commands followed by their errors, one per line.
+func (e *GroupError) Error() string {
+	return fmt.Sprintf("%d of %d commands failed:\n%s", len(e.Errors), e.Total, errors.Join(e.Errors...))
+}
+
+// Unwrap returns the errors of the failed commands, for errors.Is and errors.As.
+func (e *GroupError) Unwrap() []error {
+	return e.Errors
+}
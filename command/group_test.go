@@ -0,0 +1,93 @@
+package command
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup(t *testing.T) {
+	factory := NewFactory(env.NewRepository())
+
+	t.Run("prefixes output lines", func(t *testing.T) {
+		var out syncBuffer
+		var ownStdout bytes.Buffer
+		group := NewGroup(GroupOpts{Output: &out})
+		group.Add("first", factory.Create("bash", []string{"-c", "echo one; echo two >&2; printf three"}, &Opts{Stdout: &ownStdout}))
+		group.Add("second", Pipeline(
+			factory.Create("echo", []string{"piped"}, nil),
+			factory.Create("tr", []string{"a-z", "A-Z"}, nil),
+		))
+
+		require.NoError(t, group.Run())
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		sort.Strings(lines)
+		require.Equal(t, []string{"[first] one", "[first] three", "[first] two", "[second] PIPED"}, lines)
+		require.Equal(t, "one\nthree", ownStdout.String())
+	})
+
+	t.Run("limits concurrency", func(t *testing.T) {
+		dir := t.TempDir()
+		group := NewGroup(GroupOpts{Concurrency: 2})
+		for i := 0; i < 6; i++ {
+			// Every command records the number of commands running at its start.
+			script := `touch "$1/$$"; ls "$1" | grep -v count | wc -l >> "$1/count"; sleep 0.2; rm "$1/$$"`
+			group.Add("cmd", factory.Create("bash", []string{"-c", script, "bash", dir}, nil))
+		}
+
+		require.NoError(t, group.Run())
+
+		b, err := os.ReadFile(filepath.Join(dir, "count"))
+		require.NoError(t, err)
+		counts := strings.Fields(string(b))
+		require.Len(t, counts, 6)
+		for _, count := range counts {
+			running, err := strconv.Atoi(count)
+			require.NoError(t, err)
+			require.LessOrEqual(t, running, 2)
+		}
+	})
+
+	t.Run("collects all errors", func(t *testing.T) {
+		group := NewGroup(GroupOpts{})
+		group.Add("first", factory.Create("bash", []string{"testdata/exit_42.sh"}, nil))
+		group.Add("second", factory.Create("true", nil, nil))
+		group.Add("third", factory.Create("false", nil, nil))
+
+		err := group.Run()
+		var groupErr *GroupError
+		require.ErrorAs(t, err, &groupErr)
+		require.Equal(t, 3, groupErr.Total)
+		require.Len(t, groupErr.Errors, 2)
+		require.True(t, strings.HasPrefix(groupErr.Errors[0].Error(), "first: "))
+		require.True(t, strings.HasPrefix(groupErr.Errors[1].Error(), "third: "))
+
+		var exitErr *ExitStatusError
+		require.ErrorAs(t, err, &exitErr)
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		group := NewGroup(GroupOpts{Concurrency: 2, FailFast: true})
+		group.Add("slow", factory.Create("sleep", []string{"10"}, nil))
+		group.Add("failing", factory.Create("bash", []string{"-c", "sleep 0.1; exit 1"}, nil))
+		group.Add("skipped", factory.Create("sleep", []string{"10"}, nil))
+
+		start := time.Now()
+		err := group.Run()
+		require.Less(t, time.Since(start), 5*time.Second)
+
+		var groupErr *GroupError
+		require.ErrorAs(t, err, &groupErr)
+		require.Len(t, groupErr.Errors, 1)
+		require.True(t, strings.HasPrefix(groupErr.Errors[0].Error(), "failing: "))
+	})
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"fmt"
+	"time"
+)
+
+// LimitResource is a resource limited by Opts.Limits.
+type LimitResource string
+
+const (
+	// LimitCPUTime is the CPU time of the command (Limits.CPUTime).
+	LimitCPUTime LimitResource = "CPU time"
+	// LimitMemory is the memory of the command (Limits.MemoryBytes).
+	LimitMemory LimitResource = "memory"
+)
+
+// Limits caps the resources of a command (Linux only).
+// The rlimits are set by the prlimit utility (util-linux) before the command is executed,
+// the cgroup is joined when the process is created. The limits are inherited by the descendants.
+type Limits struct {
+	// MemoryBytes caps the memory of the command: the memory of the cgroup with UseCgroup,
+	// otherwise the virtual address space of each process (RLIMIT_AS).
+	MemoryBytes uint64
+	// CPUTime caps the CPU time of each process (RLIMIT_CPU) in whole seconds, rounded up:
+	// the process receives SIGXCPU when it exceeds the limit and SIGKILL a second later.
+	CPUTime time.Duration
+	// OpenFiles caps the number of open file descriptors of each process (RLIMIT_NOFILE).
+	OpenFiles uint64
+	// Processes caps the number of processes: of the cgroup with UseCgroup,
+	// otherwise of the user running the command (RLIMIT_NPROC, not enforced for root).
+	Processes uint64
+	// UseCgroup starts the command in a new cgroup v2 sub-group of the current process' cgroup,
+	// which limits the whole process tree. Falls back to rlimits when cgroup v2 is not available or not writable.
+	UseCgroup bool
+}
+
+// LimitExceededError is returned when a command was killed for exceeding one of its Opts.Limits.
+// Exceeding the memory limit is detected when the command runs in a cgroup (see Limits.UseCgroup).
+type LimitExceededError struct {
+	// Resource is the exceeded resource.
+	Resource LimitResource
+
+	printableCmdArgs string
+	exitErr          error
+}
+
+// Error ...
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("command killed for exceeding its %s limit (%s)", e.Resource, e.printableCmdArgs)
+}
+
+// Unwrap returns the *ExitStatusError of the command.
+func (e *LimitExceededError) Unwrap() error {
+	return e.exitErr
+}
@@ -0,0 +1,207 @@
+//go:build linux
+
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+var cgroupCounter atomic.Uint64
+
+// limitSession applies the Limits to a process.
+type limitSession struct {
+	limits Limits
+
+	cgroupDir string
+	cgroupFD  *os.File
+
+	// path and args are the command wrapped by prlimit.
+	path string
+	args []string
+}
+
+// newLimitSession prepares the limits, places cmd into a new cgroup if requested and available,
+// and wraps it with prlimit when rlimits are needed, so they are set before the command is executed.
+func newLimitSession(limits Limits, cmd *exec.Cmd) (*limitSession, error) {
+	s := &limitSession{limits: limits}
+	if limits.UseCgroup {
+		// cgroup v2 is optional, rlimits are used without it.
+		_ = s.createCgroup()
+	}
+
+	if rlimits := s.rlimitArgs(); len(rlimits) > 0 && cmd.Err == nil {
+		prlimit, err := exec.LookPath("prlimit")
+		if err != nil {
+			s.release()
+			return nil, fmt.Errorf("resource limits require the prlimit utility (util-linux): %w", err)
+		}
+
+		s.path, s.args = cmd.Path, cmd.Args
+		cmd.Path = prlimit
+		cmd.Args = append(append(append([]string{"prlimit"}, rlimits...), "--", s.path), s.args[1:]...)
+	}
+
+	if s.cgroupFD != nil {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		// The process joins the cgroup when it is created.
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(s.cgroupFD.Fd())
+	}
+	return s, nil
+}
+
+// rlimitArgs returns the prlimit options of the rlimits.
+func (s *limitSession) rlimitArgs() []string {
+	var args []string
+	if s.limits.CPUTime > 0 {
+		seconds := uint64((s.limits.CPUTime + 999_999_999) / 1_000_000_000)
+		args = append(args, fmt.Sprintf("--cpu=%d:%d", seconds, seconds+1))
+	}
+	if s.limits.OpenFiles > 0 {
+		args = append(args, fmt.Sprintf("--nofile=%d", s.limits.OpenFiles))
+	}
+	if s.cgroupDir == "" {
+		if s.limits.MemoryBytes > 0 {
+			args = append(args, fmt.Sprintf("--as=%d", s.limits.MemoryBytes))
+		}
+		if s.limits.Processes > 0 {
+			args = append(args, fmt.Sprintf("--nproc=%d", s.limits.Processes))
+		}
+	}
+	return args
+}
+
+func (s *limitSession) createCgroup() error {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return err
+	}
+	current, err := currentCgroup()
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("go-utils-command-%d-%d", os.Getpid(), cgroupCounter.Add(1))
+	dir := filepath.Join(cgroupRoot, current, name)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return err
+	}
+	s.cgroupDir = dir
+
+	if s.limits.MemoryBytes > 0 {
+		if err := writeCgroupFile(dir, "memory.max", s.limits.MemoryBytes); err != nil {
+			s.release()
+			return err
+		}
+		// Without swap the processes are OOM killed when reaching the limit.
+		_ = writeCgroupFile(dir, "memory.swap.max", 0)
+	}
+	if s.limits.Processes > 0 {
+		if err := writeCgroupFile(dir, "pids.max", s.limits.Processes); err != nil {
+			s.release()
+			return err
+		}
+	}
+
+	fd, err := os.Open(dir)
+	if err != nil {
+		s.release()
+		return err
+	}
+	s.cgroupFD = fd
+	return nil
+}
+
+// started restores the command wrapped by prlimit once cmd is started, and closes the cgroup.
+func (s *limitSession) started(cmd *exec.Cmd) {
+	if s.path != "" {
+		cmd.Path, cmd.Args = s.path, s.args
+	}
+	if s.cgroupFD != nil {
+		// The child is already in the cgroup.
+		_ = s.cgroupFD.Close()
+		s.cgroupFD = nil
+	}
+}
+
+// exceeded returns the limit the exited process was killed for, empty if none.
+// A SIGKILL is attributed to the CPU time limit if the process used it up, so it is not called for processes stopped by the command.
+func (s *limitSession) exceeded(state *os.ProcessState) LimitResource {
+	if s.cgroupDir != "" && s.limits.MemoryBytes > 0 {
+		if oomKills, err := readCgroupEvent(s.cgroupDir, "memory.events", "oom_kill"); err == nil && oomKills > 0 {
+			return LimitMemory
+		}
+	}
+
+	if s.limits.CPUTime > 0 && state != nil {
+		switch exitSignal(state) {
+		case syscall.SIGXCPU:
+			return LimitCPUTime
+		case syscall.SIGKILL:
+			if state.UserTime()+state.SystemTime() >= s.limits.CPUTime {
+				return LimitCPUTime
+			}
+		}
+	}
+
+	return ""
+}
+
+// release removes the cgroup, its processes need to be exited.
+func (s *limitSession) release() {
+	if s.cgroupFD != nil {
+		_ = s.cgroupFD.Close()
+		s.cgroupFD = nil
+	}
+	if s.cgroupDir != "" {
+		_ = os.Remove(s.cgroupDir)
+		s.cgroupDir = ""
+	}
+}
+
+// currentCgroup returns the cgroup v2 path of the current process, relative to the cgroup root.
+func currentCgroup() (string, error) {
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", errors.New("cgroup v2 path not found")
+}
+
+func writeCgroupFile(dir, name string, value uint64) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(strconv.FormatUint(value, 10)), 0o644)
+}
+
+func readCgroupEvent(dir, name, event string) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == event {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("%s not found in %s", event, name)
+}
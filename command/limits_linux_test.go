@@ -0,0 +1,75 @@
+//go:build linux
+
+package command
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits(t *testing.T) {
+	factory := NewFactory(env.NewRepository())
+
+	t.Run("rlimits", func(t *testing.T) {
+		cmd := factory.Create("bash", []string{"-c", "ulimit -n; ulimit -v; ulimit -t"}, &Opts{
+			Limits: &Limits{
+				OpenFiles:   64,
+				MemoryBytes: 512 * 1024 * 1024,
+				CPUTime:     1500 * time.Millisecond,
+			},
+		})
+
+		out, err := cmd.RunAndReturnTrimmedOutput()
+		require.NoError(t, err)
+		require.Equal(t, "64\n524288\n2", out)
+	})
+
+	t.Run("CPU time exceeded", func(t *testing.T) {
+		cmd := factory.Create("bash", []string{"-c", "while :; do :; done"}, &Opts{
+			Limits: &Limits{CPUTime: time.Second},
+		})
+
+		start := time.Now()
+		err := cmd.Run()
+		require.Less(t, time.Since(start), 10*time.Second)
+
+		var limitErr *LimitExceededError
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, LimitCPUTime, limitErr.Resource)
+		require.EqualError(t, err, `command killed for exceeding its CPU time limit (bash "-c" "while :; do :; done")`)
+
+		var exitErr *ExitStatusError
+		require.ErrorAs(t, err, &exitErr)
+	})
+
+	t.Run("stop reason takes precedence", func(t *testing.T) {
+		// The process ignores SIGXCPU and SIGTERM, so it is killed by the escalation of Timeout after using up its CPU time.
+		cmd := factory.Create("bash", []string{"-c", "trap '' XCPU TERM; while :; do :; done"}, &Opts{
+			Limits:               &Limits{CPUTime: time.Second},
+			Timeout:              1500 * time.Millisecond,
+			TerminateGracePeriod: 100 * time.Millisecond,
+		})
+
+		err := cmd.Run()
+		require.ErrorIs(t, err, ErrTimedOut)
+		var limitErr *LimitExceededError
+		require.False(t, errors.As(err, &limitErr))
+	})
+
+	t.Run("other failures", func(t *testing.T) {
+		cmd := factory.Create("bash", []string{"testdata/exit_42.sh"}, &Opts{
+			Limits: &Limits{CPUTime: time.Second, UseCgroup: true},
+		})
+
+		err := cmd.Run()
+		var limitErr *LimitExceededError
+		require.False(t, errors.As(err, &limitErr))
+		var exitErr *ExitStatusError
+		require.ErrorAs(t, err, &exitErr)
+		require.Equal(t, 42, exitErr.ExitCode())
+	})
+}
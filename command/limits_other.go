@@ -0,0 +1,24 @@
+//go:build !linux
+
+package command
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+type limitSession struct{}
+
+// newLimitSession returns an error, resource limits are only supported on Linux.
+func newLimitSession(_ Limits, _ *exec.Cmd) (*limitSession, error) {
+	return nil, errors.New("resource limits are not supported on this platform")
+}
+
+func (s *limitSession) started(_ *exec.Cmd) {}
+
+func (s *limitSession) exceeded(_ *os.ProcessState) LimitResource {
+	return ""
+}
+
+func (s *limitSession) release() {}
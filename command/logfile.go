@@ -0,0 +1,161 @@
+package command
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultLogFileMaxBackups is the number of rotated parts of a log file kept by default.
+const DefaultLogFileMaxBackups = 5
+
+// LogFileOpts configures the file the output of a command is mirrored into.
+type LogFileOpts struct {
+	// Path of the log file, missing parent directories are created.
+	// The output of every run of the command is appended to the file.
+	Path string
+	// MaxSize is the size in bytes after the file is rotated: it is gzipped to Path.1.gz
+	// (the older parts are shifted to Path.2.gz, Path.3.gz...) and a new file is started. 0 disables rotation.
+	MaxSize int64
+	// MaxBackups is the number of rotated parts kept, the older ones are deleted.
+	// Defaults to DefaultLogFileMaxBackups.
+	MaxBackups int
+}
+
+// rotatingFile is an io.Writer appending to a file, which is rotated when it reaches its size limit.
+type rotatingFile struct {
+	opts LogFileOpts
+
+	mux  sync.Mutex
+	file *os.File
+	size int64
+	err  error
+}
+
+func newRotatingFile(opts LogFileOpts) *rotatingFile {
+	if opts.MaxBackups <= 0 {
+		opts.MaxBackups = DefaultLogFileMaxBackups
+	}
+	return &rotatingFile{opts: opts}
+}
+
+func (f *rotatingFile) open() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return f.openFile()
+}
+
+func (f *rotatingFile) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(f.opts.Path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer. Failing to write the log file does not fail the command:
+// the error is kept, the output is dropped and the command's other writers still receive it.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.file == nil || f.err != nil {
+		return len(p), nil
+	}
+
+	if f.opts.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.opts.MaxSize {
+		if err := f.rotate(); err != nil {
+			f.err = err
+			return len(p), nil
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		f.err = err
+	}
+	return len(p), nil
+}
+
+// Close closes the file and returns the first error which occurred while writing it.
+func (f *rotatingFile) Close() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.file != nil {
+		if err := f.file.Close(); err != nil && f.err == nil {
+			f.err = err
+		}
+		f.file = nil
+	}
+	return f.err
+}
+
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	_ = os.Remove(f.backupPath(f.opts.MaxBackups))
+	for i := f.opts.MaxBackups - 1; i > 0; i-- {
+		if err := os.Rename(f.backupPath(i), f.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := gzipFile(f.opts.Path, f.backupPath(1)); err != nil {
+		return err
+	}
+	if err := os.Remove(f.opts.Path); err != nil {
+		return err
+	}
+
+	return f.openFile()
+}
+
+func (f *rotatingFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d.gz", f.opts.Path, i)
+}
+
+func gzipFile(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,93 @@
+package command
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+func readGzip(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	b, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "cmd.log")
+	f := newRotatingFile(LogFileOpts{Path: path, MaxSize: 10, MaxBackups: 2})
+	require.NoError(t, f.open())
+
+	for _, chunk := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "fourth\n", string(b))
+	require.Equal(t, "third\n", readGzip(t, path+".1.gz"))
+	require.Equal(t, "second\n", readGzip(t, path+".2.gz"))
+	require.NoFileExists(t, path+".3.gz")
+}
+
+func TestLogFileOpt(t *testing.T) {
+	factory := NewFactory(env.NewRepository())
+	path := filepath.Join(t.TempDir(), "cmd.log")
+
+	var stdout bytes.Buffer
+	cmd := factory.Create("bash", []string{"testdata/exit_with_message.sh"}, &Opts{
+		Stdout:  &stdout,
+		LogFile: &LogFileOpts{Path: path},
+	})
+
+	err := cmd.Run()
+	var exitErr *ExitStatusError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, path, exitErr.LogFile())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(b), "Error: first error")
+	require.Contains(t, string(b), "Error: fourth error")
+	require.Contains(t, stdout.String(), "Error: first error")
+
+	t.Run("output methods", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cmd.log")
+		cmd := factory.Create("echo", []string{"hello"}, &Opts{LogFile: &LogFileOpts{Path: path}})
+
+		out, err := cmd.RunAndReturnTrimmedOutput()
+		require.NoError(t, err)
+		require.Equal(t, "hello", out)
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "hello\n", string(b))
+	})
+
+	t.Run("pipeline stage", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cmd.log")
+		err := Pipeline(
+			factory.Create("echo", []string{"hello"}, nil),
+			factory.Create("bash", []string{"testdata/exit_42.sh"}, &Opts{LogFile: &LogFileOpts{Path: path}}),
+		).Run()
+
+		var exitErr *ExitStatusError
+		require.ErrorAs(t, err, &exitErr)
+		require.Equal(t, path, exitErr.LogFile())
+	})
+}
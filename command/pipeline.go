@@ -0,0 +1,477 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pipeline connects the stdout of each command to the stdin of the next one, like `cmd1 | cmd2` in a shell.
+// The commands need to be created by a Factory of this package, and only the first one may have Opts.Stdin
+// or Opts.UsePTY set.
+//
+// The pipeline fails like a shell pipeline with pipefail enabled: the error of the rightmost failing command
+// is returned, an *ExitStatusError reports the failed stage with PipelineStage.
+// The output methods (RunAndReturnTrimmedOutput, RunWithResult...) capture the stdout of the last command
+// and the stderr of every command.
+func Pipeline(cmds ...Command) Command {
+	return PipelineWithContext(context.Background(), cmds...)
+}
+
+// PipelineWithContext returns a Pipeline which terminates all of its commands when ctx is done.
+func PipelineWithContext(ctx context.Context, cmds ...Command) Command {
+	p := &pipeline{ctx: ctx}
+	if len(cmds) == 0 {
+		p.err = errors.New("empty pipeline")
+		return p
+	}
+
+	for i, cmd := range cmds {
+		stage, ok := cmd.(*command)
+		if !ok {
+			if p.err == nil {
+				p.err = fmt.Errorf("pipeline stage %d: unsupported Command implementation: %T", i+1, cmd)
+			}
+			continue
+		}
+		if i > 0 && stage.cmd.Stdin != nil && p.err == nil {
+			p.err = fmt.Errorf("pipeline stage %d: stdin already set", i+1)
+		}
+		if i > 0 && stage.opts.UsePTY && p.err == nil {
+			p.err = fmt.Errorf("pipeline stage %d: only the first stage can use a PTY", i+1)
+		}
+		p.stages = append(p.stages, stage)
+	}
+
+	return p
+}
+
+type pipeline struct {
+	ctx    context.Context
+	stages []*command
+	err    error
+
+	// writers are the parent's copies of the write end of the pipes, writers[i] is the stdout of stages[i].
+	writers []*os.File
+	done    chan struct{}
+}
+
+// pipelineCancelError is the stop reason of the stages terminated because the context of the pipeline is done.
+type pipelineCancelError struct {
+	err error
+}
+
+func (e *pipelineCancelError) Error() string {
+	return e.err.Error()
+}
+
+func (e *pipelineCancelError) Unwrap() error {
+	return e.err
+}
+
+// pipelineStageError is the raw error of a failed stage.
+type pipelineStageError struct {
+	index int
+	err   error
+}
+
+func (e *pipelineStageError) Error() string {
+	return fmt.Sprintf("pipeline stage %d: %s", e.index+1, e.err)
+}
+
+func (e *pipelineStageError) Unwrap() error {
+	return e.err
+}
+
+// PrintableCommandArgs ...
+func (p *pipeline) PrintableCommandArgs() string {
+	var stages []string
+	for _, stage := range p.stages {
+		stages = append(stages, stage.PrintableCommandArgs())
+	}
+	return strings.Join(stages, " | ")
+}
+
+// Run ...
+func (p *pipeline) Run() error {
+	p.prepare()
+	if err := p.run(); err != nil {
+		return p.wrapError(err)
+	}
+
+	return nil
+}
+
+// RunAndReturnExitCode ...
+func (p *pipeline) RunAndReturnExitCode() (int, error) {
+	p.prepare()
+	err := p.run()
+	if err != nil {
+		err = p.wrapError(err)
+	}
+
+	return p.exitCode(), err
+}
+
+// RunAndReturnTrimmedOutput ...
+func (p *pipeline) RunAndReturnTrimmedOutput() (string, error) {
+	p.prepare()
+	var stdout bytes.Buffer
+	if err := p.setLastStdout(&stdout); err != nil {
+		return "", p.wrapError(err)
+	}
+
+	err := p.run()
+	if err != nil {
+		err = p.wrapError(err)
+	}
+
+	return strings.TrimSpace(stdout.String()), err
+}
+
+// RunAndReturnTrimmedCombinedOutput ...
+func (p *pipeline) RunAndReturnTrimmedCombinedOutput() (string, error) {
+	p.prepare()
+	var b syncBuffer
+	if err := p.setLastStdout(&b); err != nil {
+		return "", p.wrapError(err)
+	}
+	for i, stage := range p.stages {
+		if stage.cmd.Stderr != nil {
+			return "", p.wrapError(&pipelineStageError{index: i, err: errors.New("exec: Stderr already set")})
+		}
+		stage.cmd.Stderr = &b
+	}
+
+	err := p.run()
+	if err != nil {
+		err = p.wrapError(err)
+	}
+
+	return strings.TrimSpace(b.String()), err
+}
+
+// RunWithResult ...
+func (p *pipeline) RunWithResult() (*Result, error) {
+	p.prepare()
+	result := &Result{ExitCode: -1}
+	if p.err != nil {
+		return result, p.wrapError(p.err)
+	}
+
+	limit := p.stages[len(p.stages)-1].opts.ResultOutputLimit
+	if limit <= 0 {
+		limit = DefaultResultOutputLimit
+	}
+	stdout := newTailBuffer(limit)
+	stderr := newTailBuffer(limit)
+	combined := newTailBuffer(limit)
+
+	last := p.stages[len(p.stages)-1]
+	last.cmd.Stdout = teeWriter(last.cmd.Stdout, stdout, combined)
+	for _, stage := range p.stages {
+		stage.cmd.Stderr = teeWriter(stage.cmd.Stderr, stderr, combined)
+	}
+
+	result.StartTime = time.Now()
+	err := p.run()
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+
+	result.Stdout = stdout.Bytes()
+	result.Stderr = stderr.Bytes()
+	result.CombinedOutput = combined.Bytes()
+	result.Truncated = stdout.Truncated() || stderr.Truncated() || combined.Truncated()
+
+	result.ExitCode = p.exitCode()
+	for _, stage := range p.stages {
+		result.addProcessState(stage.cmd.ProcessState)
+	}
+
+	if err != nil {
+		return result, p.wrapError(err)
+	}
+	return result, nil
+}
+
+// Start ...
+func (p *pipeline) Start() error {
+	p.prepare()
+	if err := p.start(); err != nil {
+		return p.wrapError(err)
+	}
+
+	return nil
+}
+
+// Wait ...
+func (p *pipeline) Wait() error {
+	if err := p.wait(); err != nil {
+		return p.wrapError(err)
+	}
+
+	return nil
+}
+
+// Terminate ...
+func (p *pipeline) Terminate() error {
+	if p.done == nil {
+		return errors.New("command not started")
+	}
+
+	var errs []error
+	for _, stage := range p.stages {
+		if err := stage.Terminate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Kill ...
+func (p *pipeline) Kill() error {
+	if p.done == nil {
+		return errors.New("command not started")
+	}
+
+	var errs []error
+	for _, stage := range p.stages {
+		if err := stage.Kill(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// prepare makes every stage ready for the next run of the pipeline.
+func (p *pipeline) prepare() {
+	for _, stage := range p.stages {
+		stage.prepare()
+	}
+	p.writers = nil
+	p.done = nil
+}
+
+func (p *pipeline) run() error {
+	if err := p.start(); err != nil {
+		return err
+	}
+	return p.wait()
+}
+
+func (p *pipeline) context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+func (p *pipeline) setLastStdout(w io.Writer) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	last := len(p.stages) - 1
+	if p.stages[last].cmd.Stdout != nil {
+		return &pipelineStageError{index: last, err: errors.New("exec: Stdout already set")}
+	}
+	p.stages[last].cmd.Stdout = w
+	return nil
+}
+
+// connect creates the pipes between the stages.
+func (p *pipeline) connect() ([]*os.File, error) {
+	var readers []*os.File
+	for i := 0; i < len(p.stages)-1; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closeFiles(readers)
+			closeFiles(p.writers)
+			return nil, err
+		}
+		readers = append(readers, r)
+		p.writers = append(p.writers, w)
+
+		stage := p.stages[i]
+		if stage.cmd.Stdout != nil {
+			stage.cmd.Stdout = io.MultiWriter(w, stage.cmd.Stdout)
+		} else {
+			stage.cmd.Stdout = w
+		}
+		p.stages[i+1].cmd.Stdin = r
+	}
+	return readers, nil
+}
+
+func (p *pipeline) start() error {
+	if p.err != nil {
+		return p.err
+	}
+	ctx := p.context()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	readers, err := p.connect()
+	if err != nil {
+		return err
+	}
+	for _, stage := range p.stages {
+		stage.wrapOutputs()
+	}
+
+	for i, stage := range p.stages {
+		if ctx.Done() != nil && !stage.opts.ProcessGroup && !stage.opts.UsePTY && stage.cmd.WaitDelay == 0 {
+			// The stage is stopped by the pipeline's context, see command.start.
+			stage.cmd.WaitDelay = stage.waitDelay()
+		}
+		if err := stage.start(); err != nil {
+			closeFiles(readers[max(i-1, 0):])
+			closeFiles(p.writers)
+			for _, started := range p.stages[:i] {
+				_ = started.Kill()
+				_ = started.wait()
+			}
+			return &pipelineStageError{index: i, err: err}
+		}
+
+		// The started stage holds its own copy of its stdin.
+		if i > 0 {
+			_ = readers[i-1].Close()
+		}
+	}
+
+	p.done = make(chan struct{})
+	if ctx.Done() != nil {
+		go p.watch(ctx)
+	}
+
+	return nil
+}
+
+func (p *pipeline) wait() error {
+	if p.done == nil {
+		return errors.New("command not started")
+	}
+
+	errs := make([]error, len(p.stages))
+	var wg sync.WaitGroup
+	for i, stage := range p.stages {
+		wg.Add(1)
+		go func(i int, stage *command) {
+			defer wg.Done()
+
+			errs[i] = stage.wait()
+			// The next stage receives EOF once the stage exited.
+			if i < len(p.writers) {
+				_ = p.writers[i].Close()
+			}
+		}(i, stage)
+	}
+	wg.Wait()
+	close(p.done)
+
+	for i := len(errs) - 1; i >= 0; i-- {
+		if errs[i] != nil {
+			return &pipelineStageError{index: i, err: errs[i]}
+		}
+	}
+	return nil
+}
+
+// watch terminates every stage when ctx is done before the pipeline finishes.
+func (p *pipeline) watch(ctx context.Context) {
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		reason := &pipelineCancelError{err: ctx.Err()}
+		for _, stage := range p.stages {
+			_ = stage.terminate(stage.execution, reason)
+		}
+	}
+}
+
+// exitCode returns the exit code of the rightmost failed stage, -1 if a stage did not exit normally
+// or the pipeline is invalid.
+func (p *pipeline) exitCode() int {
+	if p.err != nil {
+		return -1
+	}
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		state := p.stages[i].cmd.ProcessState
+		if state == nil || state.ExitCode() != 0 {
+			return state.ExitCode()
+		}
+	}
+	return 0
+}
+
+func (p *pipeline) wrapError(err error) error {
+	var stageErr *pipelineStageError
+	if !errors.As(err, &stageErr) && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		// The context was done before the pipeline started.
+		return p.contextError(err)
+	}
+
+	if stageErr != nil {
+		stage := p.stages[stageErr.index]
+		wrapped := stage.wrapError(stageErr.err)
+		// The pipeline is reported cancelled only if the failed stage was killed by watch: it did not exit,
+		// or stop for its own reasons (like its Opts.Timeout) before the context was done.
+		var cancelErr *pipelineCancelError
+		if stage.execution != nil && errors.As(stage.execution.stopReason(), &cancelErr) {
+			if state := stage.cmd.ProcessState; state == nil || !state.Exited() {
+				return p.contextError(cancelErr.err)
+			}
+			wrapped = stage.wrapProcessError(stageErr.err)
+		}
+
+		var exitErr *ExitStatusError
+		if errors.As(wrapped, &exitErr) {
+			return exitErr.atPipelineStage(stageErr.index+1, p.PrintableCommandArgs())
+		}
+		return fmt.Errorf("pipeline stage %d failed (%s): %w", stageErr.index+1, p.PrintableCommandArgs(), wrapped)
+	}
+
+	return fmt.Errorf("executing pipeline failed (%s): %w", p.PrintableCommandArgs(), err)
+}
+
+func (p *pipeline) contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w (%s): %w", ErrTimedOut, p.PrintableCommandArgs(), err)
+	}
+	return fmt.Errorf("%w (%s): %w", ErrCancelled, p.PrintableCommandArgs(), err)
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes.
+type syncBuffer struct {
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write implements io.Writer.
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.buf.String()
+}
@@ -0,0 +1,160 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline(t *testing.T) {
+	factory := NewFactory(env.NewRepository())
+
+	t.Run("output", func(t *testing.T) {
+		p := Pipeline(
+			factory.Create("printf", []string{"iPhone 15\\niPad Air\\niPhone 15 Pro\\n"}, nil),
+			factory.Create("grep", []string{"iPhone"}, nil),
+			factory.Create("sort", []string{"-r"}, nil),
+		)
+
+		require.Equal(t, `printf "iPhone 15\niPad Air\niPhone 15 Pro\n" | grep "iPhone" | sort "-r"`, p.PrintableCommandArgs())
+		out, err := p.RunAndReturnTrimmedOutput()
+		require.NoError(t, err)
+		require.Equal(t, "iPhone 15 Pro\niPhone 15", out)
+	})
+
+	t.Run("stdin of the first stage and stdout of the last stage", func(t *testing.T) {
+		var out bytes.Buffer
+		p := Pipeline(
+			factory.Create("cat", nil, &Opts{Stdin: bytes.NewBufferString("b\na\n")}),
+			factory.Create("sort", nil, &Opts{Stdout: &out}),
+		)
+
+		require.NoError(t, p.Run())
+		require.Equal(t, "a\nb\n", out.String())
+	})
+
+	t.Run("pipefail", func(t *testing.T) {
+		p := Pipeline(
+			factory.Create("bash", []string{"-c", "echo first; exit 3"}, nil),
+			factory.Create("grep", []string{"not-found"}, nil),
+			factory.Create("cat", nil, nil),
+		)
+
+		exitCode, err := p.RunAndReturnExitCode()
+		require.Equal(t, 1, exitCode)
+		var exitErr *ExitStatusError
+		require.True(t, errors.As(err, &exitErr))
+		require.Equal(t, 2, exitErr.PipelineStage())
+		require.EqualError(t, err, `pipeline stage 2 failed (bash "-c" "echo first; exit 3" | grep "not-found" | cat): command failed with exit status 1 (grep "not-found"): check the command's output for details`)
+	})
+
+	t.Run("combined output", func(t *testing.T) {
+		p := Pipeline(
+			factory.Create("bash", []string{"-c", "echo stderr >&2; echo stdout"}, nil),
+			factory.Create("cat", nil, nil),
+		)
+
+		out, err := p.RunAndReturnTrimmedCombinedOutput()
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"stderr", "stdout"}, strings.Fields(out))
+	})
+
+	t.Run("result", func(t *testing.T) {
+		p := Pipeline(
+			factory.Create("bash", []string{"-c", "echo b; echo a; echo err >&2"}, nil),
+			factory.Create("sort", nil, nil),
+		)
+
+		result, err := p.RunWithResult()
+		require.NoError(t, err)
+		require.Equal(t, 0, result.ExitCode)
+		require.Equal(t, "a\nb\n", string(result.Stdout))
+		require.Equal(t, "err\n", string(result.Stderr))
+	})
+
+	t.Run("downstream exits early", func(t *testing.T) {
+		p := Pipeline(
+			factory.Create("yes", nil, nil),
+			factory.Create("head", []string{"-n", "2"}, nil),
+		)
+
+		out, err := p.RunAndReturnTrimmedOutput()
+		require.Equal(t, "y\ny", out)
+		var exitErr *ExitStatusError
+		require.True(t, errors.As(err, &exitErr), err)
+		require.Equal(t, 1, exitErr.PipelineStage())
+		require.EqualError(t, err, `pipeline stage 1 failed (yes | head "-n" "2"): command failed with signal: broken pipe (yes): check the command's output for details`)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		p := PipelineWithContext(ctx,
+			factory.Create("sleep", []string{"10"}, nil),
+			factory.Create("cat", nil, nil),
+		)
+
+		err := p.Run()
+		require.ErrorIs(t, err, ErrTimedOut)
+		require.EqualError(t, err, `command timed out (sleep "10" | cat): context deadline exceeded`)
+	})
+
+	t.Run("stage timed out in a cancellable pipeline", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		p := PipelineWithContext(ctx,
+			factory.Create("sleep", []string{"10"}, &Opts{Timeout: 100 * time.Millisecond}),
+			factory.Create("cat", nil, nil),
+		)
+
+		err := p.Run()
+		require.ErrorIs(t, err, ErrTimedOut)
+		require.EqualError(t, err, `pipeline stage 1 failed (sleep "10" | cat): command timed out (sleep "10"): context deadline exceeded`)
+	})
+
+	t.Run("cancelled after a stage failed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := PipelineWithContext(ctx,
+			factory.Create("bash", []string{"-c", "exit 3"}, nil),
+			factory.Create("true", nil, nil),
+		)
+
+		require.NoError(t, p.Start())
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+		err := p.Wait()
+		var exitErr *ExitStatusError
+		require.True(t, errors.As(err, &exitErr), err)
+		require.Equal(t, 3, exitErr.ExitCode())
+	})
+
+	t.Run("invalid stage", func(t *testing.T) {
+		p := Pipeline(
+			factory.Create("echo", nil, nil),
+			factory.Create("cat", nil, &Opts{Stdin: bytes.NewBufferString("")}),
+		)
+
+		require.EqualError(t, p.Run(), `executing pipeline failed (echo | cat): pipeline stage 2: stdin already set`)
+		require.EqualError(t, Pipeline().Run(), `executing pipeline failed (): empty pipeline`)
+
+		exitCode, err := Pipeline().RunAndReturnExitCode()
+		require.Equal(t, -1, exitCode)
+		require.Error(t, err)
+	})
+
+	t.Run("not started stage", func(t *testing.T) {
+		p := Pipeline(
+			factory.Create("echo", nil, nil),
+			factory.Create("not-existing-executable", nil, nil),
+		)
+
+		err := p.Run()
+		require.ErrorContains(t, err, `pipeline stage 2 failed (echo | not-existing-executable): executing command failed (not-existing-executable)`)
+	})
+}
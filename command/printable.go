@@ -0,0 +1,91 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/redactwriter"
+)
+
+// PrintableCommandArgs returns the command line of name and args in the format of Command.PrintableCommandArgs,
+// with the secrets replaced by redactwriter.RedactStr. Useful for Command implementations, like fakes in tests.
+func PrintableCommandArgs(name string, args []string, secrets []string) string {
+	return printableCommandArgs(false, append([]string{name}, args...), secrets)
+}
+
+// printableCommandArgs returns the command line in a form which can be pasted into a POSIX shell.
+// Arguments are double-quoted when possible and single-quoted when they contain characters
+// which are special inside double quotes. The first argument (the command) is only quoted when needed,
+// unless isQuoteFirst is set. The secrets are replaced with redactwriter.RedactStr.
+func printableCommandArgs(isQuoteFirst bool, fullCommandArgs []string, secrets []string) string {
+	var cmdArgsDecorated []string
+	for idx, anArg := range fullCommandArgs {
+		anArg = redactArg(anArg, secrets)
+
+		quotedArg := quoteArg(anArg)
+		if idx == 0 && !isQuoteFirst && isBareWord(anArg) {
+			quotedArg = anArg
+		}
+		cmdArgsDecorated = append(cmdArgsDecorated, quotedArg)
+	}
+
+	return strings.Join(cmdArgsDecorated, " ")
+}
+
+func redactArg(arg string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		arg = strings.ReplaceAll(arg, secret, redactwriter.RedactStr)
+		// In line with redactwriter.New, secrets are also redacted in their escaped newline form.
+		if strings.Contains(secret, "\n") {
+			arg = strings.ReplaceAll(arg, strings.ReplaceAll(secret, "\n", `\n`), redactwriter.RedactStr)
+		}
+	}
+	return arg
+}
+
+// quoteArg double-quotes arg if its content is preserved literally inside double quotes,
+// otherwise it single-quotes arg.
+func quoteArg(arg string) string {
+	if isDoubleQuoteSafe(arg) {
+		return `"` + arg + `"`
+	}
+	// Single quotes preserve everything literally, except the single quote itself,
+	// which needs to be closed, escaped and reopened.
+	return `'` + strings.ReplaceAll(arg, `'`, `'\''`) + `'`
+}
+
+// isDoubleQuoteSafe reports whether arg has no characters with special meaning inside double quotes:
+// parameter expansion ($), command substitution (`), history expansion (!), the closing quote,
+// escaping backslashes and line breaks.
+func isDoubleQuoteSafe(arg string) bool {
+	for i := 0; i < len(arg); i++ {
+		switch arg[i] {
+		case '$', '`', '"', '!', '\n', '\r':
+			return false
+		case '\\':
+			// A backslash is only special when followed by one of $ ` " \ or a newline.
+			if i == len(arg)-1 || strings.IndexByte("$`\"\\\n", arg[i+1]) != -1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isBareWord reports whether arg can be printed without quotes.
+func isBareWord(arg string) bool {
+	if arg == "" {
+		return false
+	}
+	for _, r := range arg {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("@%+=:,./_-", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
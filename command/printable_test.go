@@ -0,0 +1,87 @@
+package command
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintableCommandArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "simple arguments",
+			args: []string{"xcodebuild", "-scheme", "My App"},
+			want: `xcodebuild "-scheme" "My App"`,
+		},
+		{
+			name: "parameter expansion",
+			args: []string{"echo", "$HOME"},
+			want: `echo '$HOME'`,
+		},
+		{
+			name: "quotes",
+			args: []string{"echo", `say "hi"`, "it's"},
+			want: `echo 'say "hi"' "it's"`,
+		},
+		{
+			name: "single quote and dollar",
+			args: []string{"echo", "it's $5"},
+			want: `echo 'it'\''s $5'`,
+		},
+		{
+			name: "newline",
+			args: []string{"echo", "first\nsecond"},
+			want: "echo 'first\nsecond'",
+		},
+		{
+			name: "backslashes",
+			args: []string{"echo", `C:\path`, `trailing\`, `double\\`},
+			want: `echo "C:\path" 'trailing\' 'double\\'`,
+		},
+		{
+			name: "history expansion and command substitution",
+			args: []string{"echo", "wow!", "`id`"},
+			want: "echo 'wow!' '`id`'",
+		},
+		{
+			name: "command with spaces",
+			args: []string{"/Applications/My Tool/bin/tool", ""},
+			want: `"/Applications/My Tool/bin/tool" ""`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := printableCommandArgs(false, tt.args, nil)
+			require.Equal(t, tt.want, got)
+
+			// The printed command line evaluates to the original arguments.
+			out, err := exec.Command("bash", "-c", "printf '%s\\0' "+printableCommandArgs(true, tt.args, nil)).Output()
+			require.NoError(t, err)
+			require.Equal(t, tt.args, strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00"))
+		})
+	}
+}
+
+func TestPrintableCommandArgs_Secrets(t *testing.T) {
+	cmd := NewFactory(env.NewRepository()).Create("curl", []string{"-H", "Authorization: token s3cr3t", "--data", "key=multi\\nline", "https://example.com"}, &Opts{
+		Secrets: []string{"s3cr3t", "multi\nline", ""},
+	})
+
+	require.Equal(t, `curl "-H" "Authorization: token [REDACTED]" "--data" "key=[REDACTED]" "https://example.com"`, cmd.PrintableCommandArgs())
+	require.Equal(t, cmd.PrintableCommandArgs(), PrintableCommandArgs("curl", []string{"-H", "Authorization: token s3cr3t", "--data", "key=multi\\nline", "https://example.com"}, []string{"s3cr3t", "multi\nline", ""}))
+}
+
+func TestPrintableCommandArgs_SecretsInError(t *testing.T) {
+	cmd := NewFactory(env.NewRepository()).Create("bash", []string{"testdata/exit_42.sh", "--token=s3cr3t"}, &Opts{
+		Secrets: []string{"s3cr3t"},
+	})
+
+	require.EqualError(t, cmd.Run(), `command failed with exit status 42 (bash "testdata/exit_42.sh" "--token=[REDACTED]"): check the command's output for details`)
+}
@@ -0,0 +1,33 @@
+//go:build !unix
+
+package command
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op, process groups are only supported on unix systems.
+func setProcessGroup(_ *exec.Cmd) {}
+
+// terminateProcess kills the process, graceful termination is only supported on unix systems.
+func terminateProcess(process *os.Process, group bool) error {
+	return killProcess(process, group)
+}
+
+func killProcess(process *os.Process, _ bool) error {
+	if process == nil {
+		return nil
+	}
+
+	err := process.Kill()
+	if errors.Is(err, os.ErrProcessDone) {
+		return nil
+	}
+	return err
+}
+
+func exitSignal(_ *os.ProcessState) os.Signal {
+	return nil
+}
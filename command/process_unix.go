@@ -0,0 +1,54 @@
+//go:build unix
+
+package command
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func terminateProcess(process *os.Process, group bool) error {
+	return signalProcess(process, group, syscall.SIGTERM)
+}
+
+func killProcess(process *os.Process, group bool) error {
+	return signalProcess(process, group, syscall.SIGKILL)
+}
+
+func signalProcess(process *os.Process, group bool, sig syscall.Signal) error {
+	if process == nil {
+		return nil
+	}
+
+	if !group {
+		err := process.Signal(sig)
+		if errors.Is(err, os.ErrProcessDone) {
+			return nil
+		}
+		return err
+	}
+
+	// A negative pid addresses every process in the process group.
+	err := syscall.Kill(-process.Pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+func exitSignal(state *os.ProcessState) os.Signal {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return nil
+	}
+	return status.Signal()
+}
@@ -0,0 +1,195 @@
+//go:build unix
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerminate(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	cmd := NewFactory(env.NewRepository()).Create("bash", []string{"-c", "sleep 30 & echo $! > " + pidFile + "; wait"}, &Opts{
+		ProcessGroup: true,
+	})
+	require.NoError(t, cmd.Start())
+	pid := waitForPID(t, pidFile)
+
+	require.NoError(t, cmd.Terminate())
+	err := cmd.Wait()
+	require.ErrorIs(t, err, ErrTerminated)
+	require.EqualError(t, err, `command terminated (bash "-c" 'sleep 30 & echo $! > `+pidFile+`; wait')`)
+	require.False(t, isRunning(pid))
+}
+
+func TestKill(t *testing.T) {
+	cmd := NewFactory(env.NewRepository()).Create("bash", []string{"-c", "trap '' TERM; sleep 30"}, &Opts{ProcessGroup: true})
+	require.EqualError(t, cmd.Kill(), "command not started")
+
+	require.NoError(t, cmd.Start())
+	require.NoError(t, cmd.Kill())
+	require.ErrorIs(t, cmd.Wait(), ErrTerminated)
+}
+
+func TestWait_StopsDescendants(t *testing.T) {
+	cmd := NewFactory(env.NewRepository()).Create("bash", []string{"-c", "sleep 30 >/dev/null 2>&1 & echo $!"}, &Opts{ProcessGroup: true})
+
+	out, err := cmd.RunAndReturnTrimmedOutput()
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(out)
+	require.NoError(t, err)
+	require.False(t, isRunning(pid))
+}
+
+func TestWait_DescendantHoldingOutput(t *testing.T) {
+	cmd := NewFactory(env.NewRepository()).Create("bash", []string{"-c", "sleep 30 & echo done"}, &Opts{ProcessGroup: true})
+
+	start := time.Now()
+	out, err := cmd.RunAndReturnTrimmedOutput()
+	require.NoError(t, err)
+	require.Equal(t, "done", out)
+	require.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestRun_StoppedDescendantHoldingOutput(t *testing.T) {
+	const gracePeriod = 500 * time.Millisecond
+	args := []string{"-c", "sleep 30; echo hi"}
+
+	tests := []struct {
+		name    string
+		create  func(opts *Opts) Command
+		opts    Opts
+		wantErr error
+	}{
+		{
+			name: "context deadline",
+			create: func(opts *Opts) Command {
+				ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+				t.Cleanup(cancel)
+				return NewFactory(env.NewRepository()).CreateWithContext(ctx, "bash", args, opts)
+			},
+			wantErr: ErrTimedOut,
+		},
+		{
+			name:    "timeout",
+			opts:    Opts{Timeout: 300 * time.Millisecond},
+			wantErr: ErrTimedOut,
+		},
+		{
+			name: "no output timeout",
+			opts: Opts{NoOutputTimeout: 300 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			opts := tt.opts
+			opts.Stdout = &stdout
+			opts.TerminateGracePeriod = gracePeriod
+
+			var cmd Command
+			if tt.create != nil {
+				cmd = tt.create(&opts)
+			} else {
+				cmd = NewFactory(env.NewRepository()).Create("bash", args, &opts)
+			}
+
+			start := time.Now()
+			err := cmd.Run()
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				var hangErr *HangError
+				require.ErrorAs(t, err, &hangErr)
+			}
+			// The orphaned sleep is not stopped, but Wait does not wait for it.
+			require.Less(t, time.Since(start), 300*time.Millisecond+gracePeriod+descendantsOutputDelay+time.Second)
+			require.Empty(t, stdout.String())
+		})
+	}
+}
+
+func TestPipeline_StoppedDescendantHoldingOutput(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	factory := NewFactory(env.NewRepository())
+	opts := &Opts{TerminateGracePeriod: 500 * time.Millisecond}
+	pipeline := PipelineWithContext(ctx,
+		factory.Create("true", nil, opts),
+		factory.Create("bash", []string{"-c", "sleep 30; echo hi"}, opts),
+	)
+
+	start := time.Now()
+	_, err := pipeline.RunAndReturnTrimmedOutput()
+	require.ErrorIs(t, err, ErrTimedOut)
+	require.Less(t, time.Since(start), 300*time.Millisecond+opts.TerminateGracePeriod+descendantsOutputDelay+time.Second)
+}
+
+func TestCreateWithContext_Descendants(t *testing.T) {
+	tests := []struct {
+		name             string
+		processGroup     bool
+		wantDescendantUp bool
+	}{
+		{name: "without process group only the process is stopped", wantDescendantUp: true},
+		{name: "process group is stopped", processGroup: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pidFile := filepath.Join(t.TempDir(), "pid")
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cmd := NewFactory(env.NewRepository()).CreateWithContext(ctx, "bash", []string{"-c", "sleep 30 & echo $! > " + pidFile + "; wait"}, &Opts{
+				ProcessGroup: tt.processGroup,
+			})
+			require.NoError(t, cmd.Start())
+			pid := waitForPID(t, pidFile)
+			t.Cleanup(func() { _ = syscall.Kill(pid, syscall.SIGKILL) })
+
+			cancel()
+			require.ErrorIs(t, cmd.Wait(), ErrCancelled)
+			require.Equal(t, tt.wantDescendantUp, isRunning(pid))
+		})
+	}
+}
+
+func waitForPID(t *testing.T, pidFile string) int {
+	var pid int
+	require.Eventually(t, func() bool {
+		content, err := os.ReadFile(pidFile)
+		if err != nil || !strings.HasSuffix(string(content), "\n") {
+			return false
+		}
+		pid, err = strconv.Atoi(strings.TrimSpace(string(content)))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return pid
+}
+
+// isRunning reports whether the process is alive, zombie processes (waiting to be reaped by init) are not considered running.
+func isRunning(pid int) bool {
+	var state string
+	for i := 0; i < 50; i++ {
+		out, err := exec.Command("ps", "-o", "stat=", "-p", strconv.Itoa(pid)).Output()
+		state = strings.TrimSpace(string(out))
+		if err != nil || state == "" || strings.HasPrefix(state, "Z") {
+			return false
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return true
+}
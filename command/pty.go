@@ -0,0 +1,116 @@
+package command
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultPTYRows is the number of rows of the pseudo-terminal used by Opts.UsePTY by default.
+	DefaultPTYRows = 24
+	// DefaultPTYCols is the number of columns of the pseudo-terminal used by Opts.UsePTY by default.
+	DefaultPTYCols = 80
+)
+
+// errReadCancelled is returned by a pollReader after Cancel.
+var errReadCancelled = errors.New("read cancelled")
+
+// PTYSize is the window size of a pseudo-terminal.
+type PTYSize struct {
+	Rows uint16
+	Cols uint16
+}
+
+// ptySession forwards the IO of a command running under a pseudo-terminal.
+type ptySession struct {
+	master     *os.File
+	outputDone chan struct{}
+	// stdin reads a Stdin file without consuming its input after the command exited, nil for other readers.
+	stdin     *pollReader
+	stdinDone chan struct{}
+}
+
+// attachPTY connects the stdin, stdout and stderr of the command to the terminal of a new pseudo-terminal.
+// The returned terminal needs to be closed after the command started.
+func (c *command) attachPTY() (*ptySession, *os.File, error) {
+	size := c.opts.PTYSize
+	if size.Rows == 0 {
+		size.Rows = DefaultPTYRows
+	}
+	if size.Cols == 0 {
+		size.Cols = DefaultPTYCols
+	}
+
+	master, tty, err := openPTY(size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session := &ptySession{master: master, outputDone: make(chan struct{})}
+	stdin, stdout := c.cmd.Stdin, c.cmd.Stdout
+	if stdout == nil {
+		stdout = io.Discard
+	}
+
+	c.cmd.Stdin = tty
+	c.cmd.Stdout = tty
+	c.cmd.Stderr = tty
+	setControllingTerminal(c.cmd)
+
+	go func() {
+		defer close(session.outputDone)
+		// Reading the master fails with EIO once every process closed the terminal.
+		_, _ = io.Copy(stdout, master)
+	}()
+	if stdin != nil {
+		if file, ok := stdin.(*os.File); ok {
+			if reader, err := newPollReader(file); err == nil {
+				session.stdin = reader
+				stdin = reader
+			}
+		}
+
+		session.stdinDone = make(chan struct{})
+		go func() {
+			defer close(session.stdinDone)
+			if session.stdin != nil {
+				defer func() { _ = session.stdin.Close() }()
+			}
+
+			if _, err := io.Copy(master, stdin); err == nil {
+				// End of transmission, read as EOF by the process in canonical mode.
+				_, _ = master.Write([]byte{4})
+			}
+		}()
+	}
+
+	return session, tty, nil
+}
+
+// wait waits for the output of the exited command to be forwarded, then closes the pseudo-terminal.
+// The output of descendants still holding the terminal is waited for delay.
+func (s *ptySession) wait(delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-s.outputDone:
+	case <-timer.C:
+	}
+	s.close()
+	<-s.outputDone
+	if s.stdin != nil {
+		<-s.stdinDone
+	}
+}
+
+// close closes the pseudo-terminal and stops forwarding the Stdin file.
+// Other Stdin readers are forwarded until their next read returns, like by exec.Cmd.
+func (s *ptySession) close() {
+	_ = s.master.Close()
+	if s.stdin != nil {
+		s.stdin.Cancel()
+	}
+}
@@ -0,0 +1,129 @@
+//go:build linux
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPTY opens a new pseudo-terminal, returns its master side and its terminal.
+func openPTY(size PTYSize) (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := master.SyscallConn()
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, err
+	}
+
+	var ptyNumber int
+	var ioctlErr error
+	// Control keeps the file in non-blocking mode, unlike File.Fd.
+	if err := conn.Control(func(fd uintptr) {
+		if ioctlErr = unix.IoctlSetPointerInt(int(fd), unix.TIOCSPTLCK, 0); ioctlErr != nil {
+			return
+		}
+		if ioctlErr = unix.IoctlSetWinsize(int(fd), unix.TIOCSWINSZ, &unix.Winsize{Row: size.Rows, Col: size.Cols}); ioctlErr != nil {
+			return
+		}
+		ptyNumber, ioctlErr = unix.IoctlGetInt(int(fd), unix.TIOCGPTN)
+	}); err != nil {
+		ioctlErr = err
+	}
+	if ioctlErr != nil {
+		_ = master.Close()
+		return nil, nil, ioctlErr
+	}
+
+	tty, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", ptyNumber), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, err
+	}
+
+	return master, tty, nil
+}
+
+// setControllingTerminal starts the command in a new session, with its stdin as the controlling terminal.
+// The session leader is also the leader of a new process group.
+func setControllingTerminal(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+}
+
+// pollReader reads a file only once it is readable, so a pending read can be cancelled
+// without consuming the input of the file (like the parent's stdin after the command exited).
+type pollReader struct {
+	file *os.File
+	fd   int
+	// wake is a pipe whose write end is closed by Cancel to interrupt poll.
+	wake       [2]int
+	cancelOnce sync.Once
+}
+
+func newPollReader(file *os.File) (*pollReader, error) {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	r := &pollReader{file: file}
+	// Control keeps the file in its blocking or non-blocking mode, unlike File.Fd.
+	if err := conn.Control(func(fd uintptr) {
+		r.fd = int(fd)
+	}); err != nil {
+		return nil, err
+	}
+	if err := unix.Pipe2(r.wake[:], unix.O_CLOEXEC); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Read waits for the file to become readable, then reads it. It returns errReadCancelled after Cancel.
+func (r *pollReader) Read(p []byte) (int, error) {
+	for {
+		fds := []unix.PollFd{
+			{Fd: int32(r.wake[0]), Events: unix.POLLIN},
+			{Fd: int32(r.fd), Events: unix.POLLIN},
+		}
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return 0, err
+		}
+		if fds[0].Revents != 0 {
+			return 0, errReadCancelled
+		}
+		if fds[1].Revents != 0 {
+			return r.file.Read(p)
+		}
+	}
+}
+
+// Cancel interrupts the pending read, the next reads fail too.
+func (r *pollReader) Cancel() {
+	r.cancelOnce.Do(func() {
+		_ = unix.Close(r.wake[1])
+	})
+}
+
+// Close releases the reader, it must not be called concurrently with Read.
+func (r *pollReader) Close() error {
+	r.Cancel()
+	return unix.Close(r.wake[0])
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package command
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsePTY(t *testing.T) {
+	factory := NewFactory(env.NewRepository())
+
+	t.Run("stdout and stderr are terminals", func(t *testing.T) {
+		cmd := factory.Create("bash", []string{"-c", "[ -t 0 ] && [ -t 1 ] && [ -t 2 ] && echo tty"}, &Opts{UsePTY: true})
+
+		out, err := cmd.RunAndReturnTrimmedOutput()
+		require.NoError(t, err)
+		require.Equal(t, "tty", out)
+	})
+
+	t.Run("window size", func(t *testing.T) {
+		cmd := factory.Create("stty", []string{"size"}, &Opts{UsePTY: true, PTYSize: PTYSize{Rows: 30, Cols: 100}})
+
+		out, err := cmd.RunAndReturnTrimmedOutput()
+		require.NoError(t, err)
+		require.Equal(t, "30 100", out)
+	})
+
+	t.Run("exit status and error finder", func(t *testing.T) {
+		var stdout bytes.Buffer
+		cmd := factory.Create("bash", []string{"testdata/exit_with_message.sh"}, &Opts{
+			UsePTY:                  true,
+			Stdout:                  &stdout,
+			LineBufferedErrorFinder: true,
+			ErrorFinder: func(out string) []string {
+				var errors []string
+				for _, line := range strings.Split(out, "\n") {
+					if strings.HasPrefix(line, "Error: ") {
+						errors = append(errors, line)
+					}
+				}
+				return errors
+			},
+		})
+
+		exitCode, err := cmd.RunAndReturnExitCode()
+		require.Equal(t, 1, exitCode)
+		var exitErr *ExitStatusError
+		require.ErrorAs(t, err, &exitErr)
+		require.Equal(t, []string{"Error: first error", "Error: second error", "Error: third error", "Error: fourth error"}, exitErr.ErrorLines())
+		require.Contains(t, stdout.String(), "Error: fourth error\r\n")
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		cmd := factory.Create("bash", []string{"-c", "read line; echo \"got $line\""}, &Opts{
+			UsePTY: true,
+			Stdin:  strings.NewReader("hello\n"),
+		})
+
+		out, err := cmd.RunAndReturnTrimmedOutput()
+		require.NoError(t, err)
+		// The terminal echoes the input.
+		require.Equal(t, "hello\r\ngot hello", out)
+	})
+
+	t.Run("stdin file is not read after the command exited", func(t *testing.T) {
+		stdinReader, stdinWriter, err := os.Pipe()
+		require.NoError(t, err)
+		defer func() { _ = stdinReader.Close() }()
+		defer func() { _ = stdinWriter.Close() }()
+
+		_, err = stdinWriter.WriteString("hello\n")
+		require.NoError(t, err)
+
+		cmd := factory.Create("bash", []string{"-c", "read line; echo \"got $line\""}, &Opts{
+			UsePTY: true,
+			Stdin:  stdinReader,
+		})
+		out, err := cmd.RunAndReturnTrimmedOutput()
+		require.NoError(t, err)
+		require.Equal(t, "hello\r\ngot hello", out)
+
+		// The next input of the parent is not consumed by the forwarding of the exited command.
+		_, err = stdinWriter.WriteString("next\n")
+		require.NoError(t, err)
+		require.NoError(t, stdinReader.SetReadDeadline(time.Now().Add(5*time.Second)))
+		b := make([]byte, 16)
+		n, err := stdinReader.Read(b)
+		require.NoError(t, err)
+		require.Equal(t, "next\n", string(b[:n]))
+	})
+}
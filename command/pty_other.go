@@ -0,0 +1,33 @@
+//go:build !linux
+
+package command
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// openPTY returns an error, pseudo-terminals are only supported on Linux.
+func openPTY(_ PTYSize) (*os.File, *os.File, error) {
+	return nil, nil, errors.New("PTY is not supported on this platform")
+}
+
+func setControllingTerminal(_ *exec.Cmd) {}
+
+// pollReader is not used without PTY support.
+type pollReader struct{}
+
+func newPollReader(_ *os.File) (*pollReader, error) {
+	return nil, errors.New("PTY is not supported on this platform")
+}
+
+func (r *pollReader) Read(_ []byte) (int, error) {
+	return 0, errReadCancelled
+}
+
+func (r *pollReader) Cancel() {}
+
+func (r *pollReader) Close() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// Result holds the details of a command execution.
+type Result struct {
+	ExitCode int
+	// Stdout, Stderr and CombinedOutput hold the last Opts.ResultOutputLimit bytes of the respective output.
+	Stdout         []byte
+	Stderr         []byte
+	CombinedOutput []byte
+	// Truncated reports whether any of the outputs exceeded Opts.ResultOutputLimit.
+	Truncated bool
+
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+
+	// MaxRSS is the maximum resident set size of the process in bytes, 0 if not available on the platform.
+	MaxRSS     int64
+	UserTime   time.Duration
+	SystemTime time.Duration
+	// Signal is the signal which terminated the process, nil if the process exited normally.
+	Signal os.Signal
+}
+
+// RunWithResult ...
+func (c *command) RunWithResult() (*Result, error) {
+	c.prepare()
+
+	limit := c.opts.ResultOutputLimit
+	if limit <= 0 {
+		limit = DefaultResultOutputLimit
+	}
+	stdout := newTailBuffer(limit)
+	stderr := newTailBuffer(limit)
+	combined := newTailBuffer(limit)
+
+	c.cmd.Stdout = teeWriter(c.cmd.Stdout, stdout, combined)
+	c.cmd.Stderr = teeWriter(c.cmd.Stderr, stderr, combined)
+	c.wrapOutputs()
+
+	result := &Result{StartTime: time.Now()}
+	err := c.run()
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+
+	result.Stdout = stdout.Bytes()
+	result.Stderr = stderr.Bytes()
+	result.CombinedOutput = combined.Bytes()
+	result.Truncated = stdout.Truncated() || stderr.Truncated() || combined.Truncated()
+
+	result.ExitCode = c.cmd.ProcessState.ExitCode()
+	result.addProcessState(c.cmd.ProcessState)
+
+	if err != nil {
+		return result, c.wrapError(err)
+	}
+	return result, nil
+}
+
+// addProcessState adds the resource usage of a finished process to the result.
+func (r *Result) addProcessState(state *os.ProcessState) {
+	if state == nil {
+		return
+	}
+
+	r.UserTime += state.UserTime()
+	r.SystemTime += state.SystemTime()
+	if rss := maxRSS(state); rss > r.MaxRSS {
+		r.MaxRSS = rss
+	}
+	if r.Signal == nil {
+		r.Signal = exitSignal(state)
+	}
+}
+
+// teeWriter returns a writer duplicating its writes to w (if not nil) and the given buffers.
+func teeWriter(w io.Writer, buffers ...io.Writer) io.Writer {
+	if w == nil {
+		return io.MultiWriter(buffers...)
+	}
+	return io.MultiWriter(append([]io.Writer{w}, buffers...)...)
+}
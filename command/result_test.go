@@ -0,0 +1,60 @@
+package command
+
+import (
+	"bytes"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWithResult(t *testing.T) {
+	factory := NewFactory(env.NewRepository())
+
+	t.Run("exit status and outputs", func(t *testing.T) {
+		var stdout bytes.Buffer
+		cmd := factory.Create("bash", []string{"testdata/exit_with_message.sh"}, &Opts{Stdout: &stdout})
+
+		result, err := cmd.RunWithResult()
+		var exitStatusErr *ExitStatusError
+		require.ErrorAs(t, err, &exitStatusErr)
+
+		require.Equal(t, 1, result.ExitCode)
+		require.Equal(t, "Error: first error\nError: second error\nThis is not an stdout error\n", string(result.Stdout))
+		require.Equal(t, "Error: third error\nError: fourth error\n", string(result.Stderr))
+		require.Len(t, result.CombinedOutput, len(result.Stdout)+len(result.Stderr))
+		require.Equal(t, stdout.String(), string(result.Stdout))
+		require.False(t, result.Truncated)
+		require.Nil(t, result.Signal)
+		require.False(t, result.StartTime.IsZero())
+		require.Equal(t, result.EndTime.Sub(result.StartTime), result.Duration)
+		require.Greater(t, result.MaxRSS, int64(0))
+	})
+
+	t.Run("output limit", func(t *testing.T) {
+		cmd := factory.Create("bash", []string{"-c", "echo 0123456789"}, &Opts{ResultOutputLimit: 4})
+
+		result, err := cmd.RunWithResult()
+		require.NoError(t, err)
+		require.Equal(t, 0, result.ExitCode)
+		require.Equal(t, "789\n", string(result.Stdout))
+		require.True(t, result.Truncated)
+	})
+
+	t.Run("killed by signal", func(t *testing.T) {
+		cmd := factory.Create("sleep", []string{"10"}, &Opts{Timeout: 100 * time.Millisecond})
+
+		result, err := cmd.RunWithResult()
+		require.ErrorIs(t, err, ErrTimedOut)
+		require.Equal(t, -1, result.ExitCode)
+		require.Equal(t, syscall.SIGTERM, result.Signal)
+	})
+
+	t.Run("invalid command", func(t *testing.T) {
+		result, err := factory.Create("", nil, nil).RunWithResult()
+		require.Error(t, err)
+		require.Equal(t, -1, result.ExitCode)
+	})
+}
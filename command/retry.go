@@ -0,0 +1,85 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/bitrise-io/go-utils/v2/retry"
+)
+
+// Attempt describes a failed run of a command, passed to a RetryPredicate.
+type Attempt struct {
+	// Number is the zero based index of the attempt.
+	Number uint
+	// ExitCode is the exit code of the command, -1 if it did not exit normally.
+	ExitCode int
+	// Err is the error returned by the run.
+	Err error
+	// ExitStatusError is set if the command exited with a non-zero status.
+	ExitStatusError *ExitStatusError
+	// ErrorLines are the lines found in the output by Opts.ErrorFinder and Opts.ErrorMatcher.
+	ErrorLines []string
+}
+
+// RetryPredicate decides whether a failed attempt should be retried.
+type RetryPredicate func(attempt Attempt) bool
+
+// RunWithRetry runs cmd with the retries and wait times of model, while shouldRetry allows it.
+// The command is recreated for every attempt, a Stdin implementing io.Seeker is rewound,
+// other readers are not replayed. A command is never retried once its context (see Factory.CreateWithContext)
+// is cancelled or its deadline is exceeded, while an attempt stopped by the command's own Opts.Timeout
+// is passed to shouldRetry. The error of the last attempt is returned.
+func RunWithRetry(cmd Command, model *retry.Model, shouldRetry RetryPredicate) error {
+	return model.TryWithAbort(func(attempt uint) (error, bool) {
+		exitCode, err := cmd.RunAndReturnExitCode()
+		if err == nil {
+			return nil, false
+		}
+		if contextDone(cmd, err) {
+			return err, true
+		}
+
+		a := Attempt{Number: attempt, ExitCode: exitCode, Err: err}
+		if errors.As(err, &a.ExitStatusError) {
+			a.ErrorLines = a.ExitStatusError.ErrorLines()
+		}
+
+		return err, !shouldRetry(a)
+	})
+}
+
+// contextDone reports whether the context of cmd is done. The context of Command implementations
+// of other packages is unknown, their attempts stopped by a context are treated as done.
+func contextDone(cmd Command, err error) bool {
+	if c, ok := cmd.(interface{ context() context.Context }); ok {
+		return c.context().Err() != nil
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// RetryOnExitCodes retries the attempts which exited with one of codes.
+func RetryOnExitCodes(codes ...int) RetryPredicate {
+	return func(attempt Attempt) bool {
+		for _, code := range codes {
+			if attempt.ExitCode == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryOnErrorLines retries the attempts with an error line matching one of patterns.
+func RetryOnErrorLines(patterns ...*regexp.Regexp) RetryPredicate {
+	return func(attempt Attempt) bool {
+		for _, line := range attempt.ErrorLines {
+			for _, pattern := range patterns {
+				if pattern.MatchString(line) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,127 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/retry"
+	"github.com/stretchr/testify/require"
+)
+
+func readCounter(t *testing.T, path string) string {
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.TrimSpace(string(b))
+}
+
+func TestRunWithRetry(t *testing.T) {
+	factory := NewFactory(env.NewRepository())
+	networkErr := RetryOnErrorLines(regexp.MustCompile("connection reset"))
+
+	t.Run("retries until success", func(t *testing.T) {
+		counter := filepath.Join(t.TempDir(), "counter")
+		var out bytes.Buffer
+		cmd := factory.Create("bash", []string{"testdata/flaky.sh", counter, "3"}, &Opts{
+			Stdout:       &out,
+			ErrorMatcher: NewPrefixErrorMatcher(MatcherContext{}),
+		})
+
+		var attempts []Attempt
+		err := RunWithRetry(cmd, retry.Times(5), func(attempt Attempt) bool {
+			attempts = append(attempts, attempt)
+			return networkErr(attempt)
+		})
+		require.NoError(t, err)
+		require.Len(t, attempts, 2)
+		require.Equal(t, uint(1), attempts[1].Number)
+		require.Equal(t, 3, attempts[1].ExitCode)
+		require.NotNil(t, attempts[1].ExitStatusError)
+		require.Equal(t, []string{"Error: connection reset"}, attempts[1].ErrorLines)
+		require.Contains(t, out.String(), "attempt 3")
+	})
+
+	t.Run("stops when the predicate declines", func(t *testing.T) {
+		counter := filepath.Join(t.TempDir(), "counter")
+		cmd := factory.Create("bash", []string{"testdata/flaky.sh", counter, "3"}, &Opts{
+			ErrorMatcher: NewPrefixErrorMatcher(MatcherContext{}),
+		})
+
+		err := RunWithRetry(cmd, retry.Times(5), RetryOnExitCodes(1, 2))
+		var exitErr *ExitStatusError
+		require.ErrorAs(t, err, &exitErr)
+		require.Equal(t, 3, exitErr.ExitCode())
+		require.Equal(t, "1", readCounter(t, counter))
+	})
+
+	t.Run("returns the last error", func(t *testing.T) {
+		counter := filepath.Join(t.TempDir(), "counter")
+		cmd := factory.Create("bash", []string{"testdata/flaky.sh", counter, "10"}, &Opts{
+			ErrorMatcher: NewPrefixErrorMatcher(MatcherContext{}),
+		})
+
+		err := RunWithRetry(cmd, retry.Times(2), networkErr)
+		require.EqualError(t, err, `command failed with exit status 3 (bash "testdata/flaky.sh" "`+counter+`" "10"): Error: connection reset`)
+		require.Equal(t, "3", readCounter(t, counter))
+	})
+
+	t.Run("rewinds stdin", func(t *testing.T) {
+		var out bytes.Buffer
+		cmd := factory.Create("bash", []string{"-c", "cat; exit 1"}, &Opts{
+			Stdin:  strings.NewReader("input\n"),
+			Stdout: &out,
+		})
+
+		_ = RunWithRetry(cmd, retry.Times(1), RetryOnExitCodes(1))
+		require.Equal(t, "input\ninput\n", out.String())
+	})
+
+	t.Run("retries the attempts timed out by Opts.Timeout", func(t *testing.T) {
+		cmd := factory.Create("sleep", []string{"10"}, &Opts{Timeout: 50 * time.Millisecond})
+
+		var attempts int
+		err := RunWithRetry(cmd, retry.Times(2), func(attempt Attempt) bool {
+			attempts++
+			return errors.Is(attempt.Err, ErrTimedOut)
+		})
+		require.ErrorIs(t, err, ErrTimedOut)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("stops when the context deadline is exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		cmd := factory.CreateWithContext(ctx, "sleep", []string{"10"}, nil)
+
+		var attempts int
+		err := RunWithRetry(cmd, retry.Times(2), func(Attempt) bool {
+			attempts++
+			return true
+		})
+		require.ErrorIs(t, err, ErrTimedOut)
+		require.Zero(t, attempts)
+	})
+
+	t.Run("pipeline", func(t *testing.T) {
+		counter := filepath.Join(t.TempDir(), "counter")
+		pipeline := Pipeline(
+			factory.Create("bash", []string{"testdata/flaky.sh", counter, "2"}, nil),
+			factory.Create("tr", []string{"a-z", "A-Z"}, nil),
+		)
+
+		var exitCodes []int
+		err := RunWithRetry(pipeline, retry.Times(3), func(attempt Attempt) bool {
+			exitCodes = append(exitCodes, attempt.ExitCode)
+			return true
+		})
+		require.NoError(t, err)
+		require.Equal(t, []int{3}, exitCodes)
+	})
+}
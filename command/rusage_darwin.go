@@ -0,0 +1,15 @@
+package command
+
+import (
+	"os"
+	"syscall"
+)
+
+func maxRSS(state *os.ProcessState) int64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || usage == nil {
+		return 0
+	}
+	// Maxrss is reported in bytes on macOS.
+	return usage.Maxrss
+}
@@ -0,0 +1,9 @@
+#!/bin/bash
+# Fails with a network error until it ran $2 times, the runs are counted in the $1 file.
+n=$(( $(cat "$1" 2>/dev/null || echo 0) + 1 ))
+echo $n > "$1"
+if [ $n -lt $2 ]; then
+  echo "Error: connection reset"
+  exit 3
+fi
+echo "attempt $n"
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchdog is an io.Writer which reports a hang when nothing was written to it for the given timeout.
+// It retains the last lines written to it, to be attached to the HangError. Lines longer than maxLineLength are split.
+type watchdog struct {
+	timeout  time.Duration
+	maxLines int
+
+	mux     sync.Mutex
+	timer   *time.Timer
+	lines   []string
+	partial []byte
+}
+
+func newWatchdog(timeout time.Duration, maxLines int) *watchdog {
+	return &watchdog{
+		timeout:  timeout,
+		maxLines: maxLines,
+	}
+}
+
+// Write implements io.Writer.
+func (w *watchdog) Write(p []byte) (int, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.timer != nil {
+		w.timer.Reset(w.timeout)
+	}
+
+	data := append(w.partial, p...)
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx == -1 {
+			break
+		}
+		w.appendLine(string(data[:idx]))
+		data = data[idx+1:]
+	}
+	for len(data) >= maxLineLength {
+		w.appendLine(string(data[:maxLineLength]))
+		data = data[maxLineLength:]
+	}
+	w.partial = append([]byte(nil), data...)
+
+	return len(p), nil
+}
+
+func (w *watchdog) appendLine(line string) {
+	w.lines = append(w.lines, strings.TrimSuffix(line, "\r"))
+	if len(w.lines) > w.maxLines {
+		w.lines = w.lines[len(w.lines)-w.maxLines:]
+	}
+}
+
+func (w *watchdog) start(onHang func(*HangError)) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.timer = time.AfterFunc(w.timeout, func() {
+		onHang(&HangError{
+			Timeout:   w.timeout,
+			LastLines: w.lastLines(),
+		})
+	})
+}
+
+func (w *watchdog) stop() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+func (w *watchdog) lastLines() []string {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	lines := append([]string(nil), w.lines...)
+	if len(w.partial) > 0 {
+		lines = append(lines, string(w.partial))
+	}
+	if len(lines) > w.maxLines {
+		lines = lines[len(lines)-w.maxLines:]
+	}
+
+	return lines
+}
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	cmd := NewFactory(env.NewRepository()).Create("sleep", []string{"10"}, &Opts{Timeout: 100 * time.Millisecond})
+
+	err := cmd.Run()
+	require.ErrorIs(t, err, ErrTimedOut)
+}
+
+func TestNoOutputTimeout(t *testing.T) {
+	var out bytes.Buffer
+	cmd := NewFactory(env.NewRepository()).Create("bash", []string{"-c", "echo first; sleep 0.1; echo second >&2; sleep 0.1; echo -n third; sleep 10"}, &Opts{
+		Stdout:          &out,
+		NoOutputTimeout: 500 * time.Millisecond,
+		HangOutputLines: 2,
+	})
+
+	err := cmd.Run()
+	var hangErr *HangError
+	require.True(t, errors.As(err, &hangErr), err)
+	require.Equal(t, []string{"second", "third"}, hangErr.LastLines)
+	require.Equal(t, `command produced no output for 500ms (bash "-c" "echo first; sleep 0.1; echo second >&2; sleep 0.1; echo -n third; sleep 10"), last output:
+second
+third`, err.Error())
+	require.Equal(t, "first\nthird", out.String())
+}
+
+func TestNoOutputTimeout_NotFiredWhileProducingOutput(t *testing.T) {
+	cmd := NewFactory(env.NewRepository()).Create("bash", []string{"-c", "for i in 1 2 3 4 5; do echo $i; sleep 0.1; done"}, &Opts{
+		NoOutputTimeout: 300 * time.Millisecond,
+	})
+
+	output, err := cmd.RunAndReturnTrimmedOutput()
+	require.NoError(t, err)
+	require.Equal(t, "1\n2\n3\n4\n5", output)
+}
+
+func TestWatchdog_lastLines(t *testing.T) {
+	w := newWatchdog(time.Minute, 3)
+	for _, chunk := range []string{"li", "ne 1\nline 2\r\n", "line 3\nline 4\nline", " 5"} {
+		_, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []string{"line 3", "line 4", "line 5"}, w.lastLines())
+}
+
+func TestWatchdog_longLine(t *testing.T) {
+	w := newWatchdog(time.Minute, 3)
+	_, err := w.Write([]byte(strings.Repeat("x", maxLineLength+10)))
+	require.NoError(t, err)
+
+	require.Len(t, w.partial, 10)
+	require.Equal(t, []string{strings.Repeat("x", maxLineLength), strings.Repeat("x", 10)}, w.lastLines())
+}
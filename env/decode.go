@@ -0,0 +1,333 @@
+package env
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/parseutil"
+)
+
+// DecodeError is returned by Decode, it holds the errors of every invalid field.
+type DecodeError struct {
+	Errors []error
+}
+
+// Error returns the errors of the fields, one per line.
+func (e *DecodeError) Error() string {
+	return "invalid environment variables:\n" + errors.Join(e.Errors...).Error()
+}
+
+// Unwrap returns the errors of the fields, for errors.Is and errors.As.
+func (e *DecodeError) Unwrap() []error {
+	return e.Errors
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// Decode fills the struct pointed to by v from the environment variables of getter, based on the field tags:
+//
+//	type Config struct {
+//		Workdir  string        `env:"BITRISE_SOURCE_DIR,required"`
+//		Verbose  bool          `env:"VERBOSE" default:"no"`
+//		Timeout  time.Duration `env:"TIMEOUT" default:"10m"`
+//		Targets  []string      `env:"TARGETS" sep:"|"`
+//		Mode     string        `env:"MODE" enum:"debug,release"`
+//		Password string        `env:"PASSWORD,secret"`
+//		Cache    CacheConfig   `envPrefix:"CACHE_"`
+//	}
+//
+// An empty variable is treated as unset: the field gets its default value, a required field is reported missing.
+// Booleans are parsed with parseutil.ParseBool, slices are split by sep (defaults to ","),
+// and types implementing encoding.TextUnmarshaler parse themselves. Pointer fields stay nil when unset.
+// Nested structs (and pointers to structs, which are allocated if nil) are decoded with the envPrefix of the field
+// prepended to their variable names. The values of secret fields are not included in the errors (see also Secrets).
+// The errors of all the fields are returned at once, in a *DecodeError.
+func Decode(getter Getter, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode target must be a non-nil pointer to a struct, got %T", v)
+	}
+
+	var errs []error
+	decodeStruct(getter, rv.Elem(), "", &errs)
+	if len(errs) > 0 {
+		return &DecodeError{Errors: errs}
+	}
+	return nil
+}
+
+// Secrets returns the non-empty values of the secret fields (see Decode) of the struct pointed to by v,
+// for example to be redacted from the output of commands. The items of slices are returned one by one,
+// other types than strings are returned if they implement encoding.TextMarshaler or fmt.Stringer.
+func Secrets(v any) []string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	var secrets []string
+	collectSecrets(rv.Elem(), &secrets)
+	return secrets
+}
+
+type fieldTag struct {
+	key      string
+	required bool
+	secret   bool
+}
+
+func parseFieldTag(tag string) fieldTag {
+	parts := strings.Split(tag, ",")
+	t := fieldTag{key: parts[0]}
+	for _, option := range parts[1:] {
+		switch strings.TrimSpace(option) {
+		case "required":
+			t.required = true
+		case "secret":
+			t.secret = true
+		}
+	}
+	return t
+}
+
+func decodeStruct(getter Getter, rv reflect.Value, prefix string, errs *[]error) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			switch {
+			case isNestedStruct(field.Type):
+				decodeStruct(getter, fv, prefix+field.Tag.Get("envPrefix"), errs)
+			case field.Type.Kind() == reflect.Pointer && isNestedStruct(field.Type.Elem()):
+				if fv.IsNil() {
+					fv.Set(reflect.New(field.Type.Elem()))
+				}
+				decodeStruct(getter, fv.Elem(), prefix+field.Tag.Get("envPrefix"), errs)
+			}
+			continue
+		}
+
+		t := parseFieldTag(tag)
+		key := prefix + t.key
+		value := getter.Get(key)
+		if value == "" {
+			value = field.Tag.Get("default")
+		}
+		if value == "" {
+			if t.required {
+				*errs = append(*errs, fmt.Errorf("required environment variable (%s) not provided", key))
+			}
+			continue
+		}
+
+		if err := decodeField(fv, field, value); err != nil {
+			if t.secret {
+				*errs = append(*errs, fmt.Errorf("invalid value of environment variable (%s): %s", key, err.typeMsg()))
+			} else {
+				*errs = append(*errs, fmt.Errorf("invalid value of environment variable (%s): %s", key, err))
+			}
+		}
+	}
+}
+
+// isNestedStruct reports whether a field of type t without an env tag is a struct decoded field by field.
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// valueError is a parsing error of a field's value.
+type valueError struct {
+	value  string
+	reason string
+	// cause is the error of an encoding.TextUnmarshaler, which might contain the value.
+	cause error
+}
+
+func (e *valueError) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%q %s: %s", e.value, e.reason, e.cause)
+	}
+	return fmt.Sprintf("%q %s", e.value, e.reason)
+}
+
+// typeMsg returns the error without the value.
+func (e *valueError) typeMsg() string {
+	return "value " + e.reason
+}
+
+func decodeField(fv reflect.Value, field reflect.StructField, value string) *valueError {
+	if fv.Kind() == reflect.Slice && !fv.Addr().Type().Implements(textUnmarshalerType) {
+		sep := field.Tag.Get("sep")
+		if sep == "" {
+			sep = ","
+		}
+
+		items := strings.Split(value, sep)
+		slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+		for i, item := range items {
+			item = strings.TrimSpace(item)
+			if err := checkEnum(field, item); err != nil {
+				return err
+			}
+			if err := decodeValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	if err := checkEnum(field, value); err != nil {
+		return err
+	}
+	return decodeValue(fv, value)
+}
+
+// checkEnum validates value against the values listed in the enum tag of the field, if any.
+func checkEnum(field reflect.StructField, value string) *valueError {
+	enum, ok := field.Tag.Lookup("enum")
+	if !ok {
+		return nil
+	}
+
+	allowed := strings.Split(enum, ",")
+	for _, a := range allowed {
+		if strings.TrimSpace(a) == value {
+			return nil
+		}
+	}
+	return &valueError{value: value, reason: "is not one of " + strings.Join(allowed, ", ")}
+}
+
+func decodeValue(fv reflect.Value, value string) *valueError {
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := decodeValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+
+	if fv.Addr().Type().Implements(textUnmarshalerType) {
+		if err := fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return &valueError{value: value, reason: "is invalid", cause: err}
+		}
+		return nil
+	}
+
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return &valueError{value: value, reason: "is not a valid duration"}
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := parseutil.ParseBool(value)
+		if err != nil {
+			return &valueError{value: value, reason: "is not a valid bool"}
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return &valueError{value: value, reason: "is not a valid " + fv.Type().String()}
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return &valueError{value: value, reason: "is not a valid " + fv.Type().String()}
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return &valueError{value: value, reason: "is not a valid " + fv.Type().String()}
+		}
+		fv.SetFloat(f)
+	default:
+		return &valueError{value: value, reason: "cannot be decoded into " + fv.Type().String()}
+	}
+	return nil
+}
+
+func collectSecrets(rv reflect.Value, secrets *[]string) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			switch {
+			case isNestedStruct(field.Type):
+				collectSecrets(fv, secrets)
+			case field.Type.Kind() == reflect.Pointer && isNestedStruct(field.Type.Elem()) && !fv.IsNil():
+				collectSecrets(fv.Elem(), secrets)
+			}
+			continue
+		}
+		if parseFieldTag(tag).secret {
+			collectSecretValue(fv, secrets)
+		}
+	}
+}
+
+// collectSecretValue appends the non-empty value of a secret field, or its items if it is a slice.
+func collectSecretValue(fv reflect.Value, secrets *[]string) {
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return
+		}
+		fv = fv.Elem()
+	}
+
+	var value string
+	switch {
+	case reflect.PointerTo(fv.Type()).Implements(textMarshalerType) && fv.CanAddr():
+		text, err := fv.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return
+		}
+		value = string(text)
+	case reflect.PointerTo(fv.Type()).Implements(stringerType) && fv.CanAddr():
+		value = fv.Addr().Interface().(fmt.Stringer).String()
+	case fv.Kind() == reflect.Slice:
+		for i := 0; i < fv.Len(); i++ {
+			collectSecretValue(fv.Index(i), secrets)
+		}
+		return
+	case fv.Kind() == reflect.String:
+		value = fv.String()
+	}
+
+	if value != "" {
+		*secrets = append(*secrets, value)
+	}
+}
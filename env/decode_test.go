@@ -0,0 +1,170 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+type cacheConfig struct {
+	Enabled bool     `env:"ENABLED" default:"yes"`
+	Paths   []string `env:"PATHS" sep:"\n"`
+}
+
+type decodeConfig struct {
+	Workdir    string        `env:"WORKDIR,required"`
+	Verbose    bool          `env:"VERBOSE"`
+	Retries    int           `env:"RETRIES" default:"3"`
+	Timeout    time.Duration `env:"TIMEOUT" default:"10m"`
+	Ratio      float64       `env:"RATIO"`
+	Targets    []string      `env:"TARGETS" sep:"|"`
+	Ports      []uint16      `env:"PORTS"`
+	Mode       string        `env:"MODE" enum:"debug,release" default:"debug"`
+	Level      level         `env:"LEVEL"`
+	Password   string        `env:"PASSWORD,secret"`
+	Token      *string       `env:"TOKEN,secret"`
+	MaxWorkers *int          `env:"MAX_WORKERS"`
+	Cache      cacheConfig   `envPrefix:"CACHE_"`
+
+	ignored string `env:"IGNORED"` //nolint:unused
+}
+
+func TestDecode(t *testing.T) {
+	repo := NewMemoryRepository([]string{
+		"WORKDIR=/src",
+		"VERBOSE=y",
+		"RATIO=0.5",
+		"TARGETS=app | lib",
+		"PORTS=80,443",
+		"MODE=release",
+		"LEVEL=high",
+		"PASSWORD=s3cret",
+		"TOKEN=t0ken",
+		"CACHE_ENABLED=false",
+		"CACHE_PATHS=~/.gradle\n~/.m2",
+		"IGNORED=value",
+	})
+
+	var cfg decodeConfig
+	require.NoError(t, Decode(repo, &cfg))
+
+	token := "t0ken"
+	require.Equal(t, decodeConfig{
+		Workdir:  "/src",
+		Verbose:  true,
+		Retries:  3,
+		Timeout:  10 * time.Minute,
+		Ratio:    0.5,
+		Targets:  []string{"app", "lib"},
+		Ports:    []uint16{80, 443},
+		Mode:     "release",
+		Level:    2,
+		Password: "s3cret",
+		Token:    &token,
+		Cache:    cacheConfig{Enabled: false, Paths: []string{"~/.gradle", "~/.m2"}},
+	}, cfg)
+	require.Equal(t, []string{"s3cret", "t0ken"}, Secrets(&cfg))
+}
+
+type apiKey string
+
+func (k *apiKey) UnmarshalText(text []byte) error {
+	if !strings.HasPrefix(string(text), "key-") {
+		return fmt.Errorf("invalid api key: %s", text)
+	}
+	*k = apiKey(text)
+	return nil
+}
+
+func (k apiKey) MarshalText() ([]byte, error) {
+	return []byte(k), nil
+}
+
+func TestDecode_NestedPointer(t *testing.T) {
+	var cfg struct {
+		Cache *cacheConfig `envPrefix:"CACHE_"`
+	}
+	require.NoError(t, Decode(NewMemoryRepository([]string{"CACHE_PATHS=a\nb"}), &cfg))
+	require.Equal(t, &cacheConfig{Enabled: true, Paths: []string{"a", "b"}}, cfg.Cache)
+}
+
+func TestSecrets(t *testing.T) {
+	var cfg struct {
+		Tokens []string `env:"TOKENS,secret"`
+		Key    apiKey   `env:"KEY,secret"`
+		Nested *struct {
+			Password string `env:"PASSWORD,secret"`
+		} `envPrefix:"NESTED_"`
+	}
+	require.NoError(t, Decode(NewMemoryRepository([]string{"TOKENS=t1,,t2", "KEY=key-123", "NESTED_PASSWORD=pass"}), &cfg))
+
+	require.Equal(t, []string{"t1", "t2", "key-123", "pass"}, Secrets(&cfg))
+}
+
+func TestDecodeErrors(t *testing.T) {
+	repo := NewMemoryRepository([]string{
+		"VERBOSE=maybe",
+		"RETRIES=three",
+		"TIMEOUT=10",
+		"PORTS=80,http",
+		"MODE=profile",
+		"LEVEL=medium",
+		"PASSWORD=s3cret",
+		"MAX_WORKERS=many",
+	})
+
+	var cfg decodeConfig
+	err := Decode(repo, &cfg)
+
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	require.Equal(t, `invalid environment variables:
+required environment variable (WORKDIR) not provided
+invalid value of environment variable (VERBOSE): "maybe" is not a valid bool
+invalid value of environment variable (RETRIES): "three" is not a valid int
+invalid value of environment variable (TIMEOUT): "10" is not a valid duration
+invalid value of environment variable (PORTS): "http" is not a valid uint16
+invalid value of environment variable (MODE): "profile" is not one of debug, release
+invalid value of environment variable (LEVEL): "medium" is invalid: unknown level
+invalid value of environment variable (MAX_WORKERS): "many" is not a valid int`, err.Error())
+
+	t.Run("secret values are not printed", func(t *testing.T) {
+		var cfg struct {
+			Port int `env:"PORT,secret"`
+		}
+		err := Decode(NewMemoryRepository([]string{"PORT=s3cret"}), &cfg)
+		require.EqualError(t, err, "invalid environment variables:\ninvalid value of environment variable (PORT): value is not a valid int")
+		require.False(t, strings.Contains(fmt.Sprint(err), "s3cret"))
+	})
+
+	t.Run("secret unmarshaler errors are not printed", func(t *testing.T) {
+		var cfg struct {
+			Key apiKey `env:"KEY,secret"`
+		}
+		err := Decode(NewMemoryRepository([]string{"KEY=s3cret"}), &cfg)
+		require.EqualError(t, err, "invalid environment variables:\ninvalid value of environment variable (KEY): value is invalid")
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		var cfg decodeConfig
+		require.EqualError(t, Decode(repo, cfg), "decode target must be a non-nil pointer to a struct, got env.decodeConfig")
+	})
+}
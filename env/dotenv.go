@@ -0,0 +1,353 @@
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Variable is an environment variable read from or written to a dotenv file.
+type Variable struct {
+	Key   string
+	Value string
+}
+
+// ConflictPolicy decides what ApplyVariables does with variables which are already set to a different value.
+type ConflictPolicy int
+
+const (
+	// ConflictOverwrite replaces the current value.
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictKeep keeps the current value.
+	ConflictKeep
+	// ConflictFail returns a *ConflictError without applying any of the variables.
+	ConflictFail
+)
+
+// ConflictError is returned by ApplyVariables with ConflictFail if some of the variables are already set.
+type ConflictError struct {
+	// Keys are the conflicting keys, in the order of the variables.
+	Keys []string
+}
+
+// Error ...
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("environment variables already set: %s", strings.Join(e.Keys, ", "))
+}
+
+var (
+	dotenvKeyPattern       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	dotenvUnquotedValueRex = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]+$`)
+)
+
+// ReadDotenv parses the variables of a dotenv file:
+//
+//	# comment
+//	export GOPATH=$HOME/go
+//	NAME=value # inline comment
+//	LITERAL='no $expansion or \escapes'
+//	MESSAGE="multiline\nvalue with \"escapes\" and ${NAME:-default}"
+//
+// The values of unquoted and double-quoted variables are expanded with Expand, from the variables
+// defined earlier in the file, then from lookup (if not nil).
+// Double-quoted values support the \n, \r, \t, \", \\ and \$ escapes, single-quoted values are literal.
+// Quoted values can span multiple lines. If a key is defined more than once, the last value is used.
+func ReadDotenv(r io.Reader, lookup Getter) ([]Variable, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := dotenvParser{src: strings.ReplaceAll(string(content), "\r\n", "\n"), line: 1, lookup: lookup, indexes: map[string]int{}}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.vars, nil
+}
+
+// ReadDotenvFile reads the variables of the dotenv file at path, see ReadDotenv.
+func ReadDotenvFile(pth string, lookup Getter) ([]Variable, error) {
+	f, err := os.Open(pth)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	vars, err := ReadDotenv(f, lookup)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pth, err)
+	}
+	return vars, nil
+}
+
+// WriteDotenv writes vars in dotenv format, one per line. Values are double-quoted and escaped when needed,
+// so reading them back with ReadDotenv returns the same values.
+func WriteDotenv(w io.Writer, vars []Variable) error {
+	bw := bufio.NewWriter(w)
+	for _, v := range vars {
+		if !dotenvKeyPattern.MatchString(v.Key) {
+			return fmt.Errorf("invalid environment variable name: %q", v.Key)
+		}
+		if _, err := fmt.Fprintf(bw, "%s=%s\n", v.Key, quoteDotenvValue(v.Value)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteDotenvFile writes vars to the dotenv file at path (see WriteDotenv), replacing its content.
+// The file is created with 0600 permissions, as the values might be secrets.
+func WriteDotenvFile(pth string, vars []Variable) error {
+	f, err := os.OpenFile(pth, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteDotenv(f, vars); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ImportDotenvFile reads the dotenv file at path, expanding the values from repository, and applies the variables
+// to repository according to policy.
+func ImportDotenvFile(repository GetSetter, pth string, policy ConflictPolicy) error {
+	vars, err := ReadDotenvFile(pth, repository)
+	if err != nil {
+		return err
+	}
+	return ApplyVariables(repository, vars, policy)
+}
+
+// ExportDotenvFile writes the variables of repository with the given keys to the dotenv file at path.
+func ExportDotenvFile(repository Getter, pth string, keys ...string) error {
+	vars := make([]Variable, 0, len(keys))
+	for _, key := range keys {
+		vars = append(vars, Variable{Key: key, Value: repository.Get(key)})
+	}
+	return WriteDotenvFile(pth, vars)
+}
+
+// ApplyVariables sets vars on repository. A variable conflicts if it is already set to a different,
+// non-empty value, policy decides what happens with the conflicting variables.
+func ApplyVariables(repository GetSetter, vars []Variable, policy ConflictPolicy) error {
+	if policy == ConflictFail {
+		var conflicts []string
+		for _, v := range vars {
+			if current := repository.Get(v.Key); current != "" && current != v.Value {
+				conflicts = append(conflicts, v.Key)
+			}
+		}
+		if len(conflicts) > 0 {
+			return &ConflictError{Keys: conflicts}
+		}
+	}
+
+	for _, v := range vars {
+		if policy == ConflictKeep && repository.Get(v.Key) != "" {
+			continue
+		}
+		if err := repository.Set(v.Key, v.Value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", v.Key, err)
+		}
+	}
+	return nil
+}
+
+func quoteDotenvValue(value string) string {
+	if dotenvUnquotedValueRex.MatchString(value) {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+type dotenvParser struct {
+	src    string
+	pos    int
+	line   int
+	lookup Getter
+
+	vars    []Variable
+	indexes map[string]int
+}
+
+func (p *dotenvParser) parse() error {
+	for {
+		p.skipBlanks()
+		if p.pos >= len(p.src) {
+			return nil
+		}
+
+		switch p.src[p.pos] {
+		case '\n':
+			p.pos++
+			p.line++
+			continue
+		case '#':
+			p.skipLine()
+			continue
+		}
+
+		line := p.line
+		if err := p.parseVariable(); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+func (p *dotenvParser) parseVariable() error {
+	if rest := p.src[p.pos:]; strings.HasPrefix(rest, "export ") || strings.HasPrefix(rest, "export\t") {
+		p.pos += len("export")
+		p.skipBlanks()
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && isNameChar(p.src[p.pos], p.pos == start) {
+		p.pos++
+	}
+	key := p.src[start:p.pos]
+	if key == "" {
+		return fmt.Errorf("invalid variable name: %q", p.restOfLine())
+	}
+
+	p.skipBlanks()
+	if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return fmt.Errorf("expected = after %s", key)
+	}
+	p.pos++
+
+	valueStart := p.pos
+	p.skipBlanks()
+
+	var value string
+	var err error
+	if p.pos < len(p.src) && (p.src[p.pos] == '\'' || p.src[p.pos] == '"') {
+		value, err = p.parseQuotedValue()
+	} else {
+		p.pos = valueStart
+		value, err = p.parseUnquotedValue()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	if i, ok := p.indexes[key]; ok {
+		p.vars[i].Value = value
+	} else {
+		p.indexes[key] = len(p.vars)
+		p.vars = append(p.vars, Variable{Key: key, Value: value})
+	}
+	return nil
+}
+
+func (p *dotenvParser) parseQuotedValue() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+
+	start := p.pos
+	for ; p.pos < len(p.src) && p.src[p.pos] != quote; p.pos++ {
+		if quote == '"' && p.src[p.pos] == '\\' && p.pos+1 < len(p.src) {
+			p.pos++
+		}
+	}
+	if p.pos >= len(p.src) {
+		return "", fmt.Errorf("unterminated quoted value")
+	}
+	raw := p.src[start:p.pos]
+	p.pos++
+
+	if rest := strings.TrimLeft(p.restOfLine(), " \t"); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected characters after quoted value: %q", rest)
+	}
+	p.skipLine()
+	p.line += strings.Count(raw, "\n")
+
+	if quote == '\'' {
+		return raw, nil
+	}
+	return Expand(getterFunc(p.lookupValue), unescapeDotenvValue(raw), nil)
+}
+
+func (p *dotenvParser) parseUnquotedValue() (string, error) {
+	raw := p.restOfLine()
+	p.skipLine()
+
+	for i := 1; i < len(raw); i++ {
+		if raw[i] == '#' && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+			raw = raw[:i]
+			break
+		}
+	}
+	return Expand(getterFunc(p.lookupValue), strings.TrimSpace(raw), nil)
+}
+
+func (p *dotenvParser) lookupValue(key string) string {
+	if i, ok := p.indexes[key]; ok {
+		return p.vars[i].Value
+	}
+	if p.lookup != nil {
+		return p.lookup.Get(key)
+	}
+	return ""
+}
+
+func (p *dotenvParser) skipBlanks() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *dotenvParser) restOfLine() string {
+	rest := p.src[p.pos:]
+	if i := strings.IndexByte(rest, '\n'); i != -1 {
+		return rest[:i]
+	}
+	return rest
+}
+
+// skipLine moves to the start of the next line.
+func (p *dotenvParser) skipLine() {
+	p.pos += len(p.restOfLine())
+	if p.pos < len(p.src) {
+		p.pos++
+		p.line++
+	}
+}
+
+// unescapeDotenvValue decodes the backslash escapes of a double-quoted value. An escaped \$ becomes $$,
+// which Expand turns into a literal $.
+func unescapeDotenvValue(raw string) string {
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i+1 == len(raw) {
+			b.WriteByte(raw[i])
+			continue
+		}
+
+		i++
+		switch raw[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\':
+			b.WriteByte(raw[i])
+		case '$':
+			b.WriteString("$$")
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(raw[i])
+		}
+	}
+	return b.String()
+}
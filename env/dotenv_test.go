@@ -0,0 +1,154 @@
+package env
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadDotenv(t *testing.T) {
+	content := `# Build settings
+export GOPATH=$HOME/go
+NAME = app # the name of the app
+EMPTY=
+HASH=a#b
+LITERAL='no $expansion or \n escapes'
+MESSAGE="first line\nsecond \"line\" of ${NAME} \$NAME"
+MULTILINE="line 1
+line 2"
+	export	TAB_INDENTED=yes
+DEFAULTED=${MISSING:-${NAME}-default}
+PATH_VALUE="${PATH}:/opt/bin"
+NAME=renamed
+`
+	vars, err := ReadDotenv(strings.NewReader(content), NewMemoryRepository([]string{"HOME=/home/user", "PATH=/usr/bin"}))
+	require.NoError(t, err)
+	require.Equal(t, []Variable{
+		{Key: "GOPATH", Value: "/home/user/go"},
+		{Key: "NAME", Value: "renamed"},
+		{Key: "EMPTY", Value: ""},
+		{Key: "HASH", Value: "a#b"},
+		{Key: "LITERAL", Value: `no $expansion or \n escapes`},
+		{Key: "MESSAGE", Value: "first line\nsecond \"line\" of app $NAME"},
+		{Key: "MULTILINE", Value: "line 1\nline 2"},
+		{Key: "TAB_INDENTED", Value: "yes"},
+		{Key: "DEFAULTED", Value: "app-default"},
+		{Key: "PATH_VALUE", Value: "/usr/bin:/opt/bin"},
+	}, vars)
+}
+
+func TestReadDotenvErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "missing separator",
+			content: "A=1\nNAME value\n",
+			wantErr: "line 2: expected = after NAME",
+		},
+		{
+			name:    "invalid name",
+			content: "1NAME=value",
+			wantErr: `line 1: invalid variable name: "1NAME=value"`,
+		},
+		{
+			name:    "unterminated quote",
+			content: "A=\"multi\nline\nB=1\n",
+			wantErr: "line 1: A: unterminated quoted value",
+		},
+		{
+			name:    "characters after quoted value",
+			content: "A='multi\nline'\nB=\"value\" extra",
+			wantErr: `line 3: B: unexpected characters after quoted value: "extra"`,
+		},
+		{
+			name:    "unterminated reference",
+			content: "A=${HOME",
+			wantErr: "line 1: A: unterminated variable reference: ${HOME",
+		},
+		{
+			name:    "invalid reference",
+			content: "A=${HOME:=x}",
+			wantErr: "line 1: A: invalid variable reference: ${HOME:=x}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadDotenv(strings.NewReader(tt.content), nil)
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestWriteDotenvRoundTrip(t *testing.T) {
+	vars := []Variable{
+		{Key: "SIMPLE", Value: "value"},
+		{Key: "URL", Value: "https://example.com/a?b=c"},
+		{Key: "EMPTY", Value: ""},
+		{Key: "SPACES", Value: "  with spaces  "},
+		{Key: "SPECIAL", Value: "quote \" backslash \\ dollar $HOME # hash 'single'"},
+		{Key: "MULTILINE", Value: "line 1\nline 2\r\n"},
+	}
+
+	var b strings.Builder
+	require.NoError(t, WriteDotenv(&b, vars))
+	require.Equal(t, `SIMPLE=value
+URL="https://example.com/a?b=c"
+EMPTY=""
+SPACES="  with spaces  "
+SPECIAL="quote \" backslash \\ dollar \$HOME # hash 'single'"
+MULTILINE="line 1\nline 2\r\n"
+`, b.String())
+
+	read, err := ReadDotenv(strings.NewReader(b.String()), NewMemoryRepository([]string{"HOME=/home/user"}))
+	require.NoError(t, err)
+	require.Equal(t, vars, read)
+
+	require.EqualError(t, WriteDotenv(&b, []Variable{{Key: "INVALID-KEY", Value: "value"}}), `invalid environment variable name: "INVALID-KEY"`)
+}
+
+func TestApplyVariables(t *testing.T) {
+	vars := []Variable{{Key: "A", Value: "new"}, {Key: "B", Value: "same"}, {Key: "C", Value: "added"}}
+	environ := []string{"A=old", "B=same"}
+
+	t.Run("overwrite", func(t *testing.T) {
+		repo := NewMemoryRepository(environ)
+		require.NoError(t, ApplyVariables(repo, vars, ConflictOverwrite))
+		require.Equal(t, []string{"A=new", "B=same", "C=added"}, repo.List())
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		repo := NewMemoryRepository(environ)
+		require.NoError(t, ApplyVariables(repo, vars, ConflictKeep))
+		require.Equal(t, []string{"A=old", "B=same", "C=added"}, repo.List())
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		repo := NewMemoryRepository(environ)
+		err := ApplyVariables(repo, vars, ConflictFail)
+
+		var conflictErr *ConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.Equal(t, []string{"A"}, conflictErr.Keys)
+		require.EqualError(t, err, "environment variables already set: A")
+		require.Equal(t, []string{"A=old", "B=same"}, repo.List())
+	})
+}
+
+func TestImportExportDotenvFile(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), ".env")
+
+	source := NewMemoryRepository([]string{"TOKEN=s3cr$t", "MESSAGE=hello\nworld"})
+	require.NoError(t, ExportDotenvFile(source, pth, "TOKEN", "MESSAGE"))
+
+	target := NewMemoryRepository([]string{"TOKEN=other"})
+	require.NoError(t, ImportDotenvFile(target, pth, ConflictKeep))
+	require.Equal(t, []string{"MESSAGE=hello\nworld", "TOKEN=other"}, target.List())
+
+	_, err := ReadDotenvFile(filepath.Join(t.TempDir(), "missing.env"), nil)
+	require.Error(t, err)
+}
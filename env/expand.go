@@ -0,0 +1,200 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultExpandMaxDepth is the default limit of the nested expansions of ExpandOpts.Recursive.
+const DefaultExpandMaxDepth = 10
+
+// ExpandOpts configures Expand.
+type ExpandOpts struct {
+	// Strict makes references to undefined variables an error, instead of expanding them to empty strings.
+	Strict bool
+	// Recursive expands the references in the values of the referenced variables as well.
+	Recursive bool
+	// MaxDepth limits the nested expansions of Recursive, to stop self-referencing variables.
+	// Defaults to DefaultExpandMaxDepth.
+	MaxDepth int
+}
+
+// UndefinedVariableError is returned by Expand for a ${VAR:?message} reference to an undefined variable,
+// and for any reference to an undefined variable in strict mode.
+type UndefinedVariableError struct {
+	Key string
+	// Message is the message of the ${VAR:?message} reference.
+	Message string
+}
+
+// Error ...
+func (e *UndefinedVariableError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s: %s", e.Key, e.Message)
+	}
+	return fmt.Sprintf("undefined environment variable: %s", e.Key)
+}
+
+// Expand replaces the variable references in s with the values from repository:
+//
+//	$VAR, ${VAR}     the value of VAR
+//	${VAR:-default}  the value of VAR, or default (expanded as well) if VAR is undefined
+//	${VAR:?message}  the value of VAR, or an *UndefinedVariableError with message if VAR is undefined
+//	$$               a literal $
+//
+// Variables with empty values are undefined. A $ not followed by a variable name is kept as is.
+// A nil opts means the zero value of ExpandOpts.
+func Expand(repository Getter, s string, opts *ExpandOpts) (string, error) {
+	e := expander{repository: repository, maxDepth: DefaultExpandMaxDepth}
+	if opts != nil {
+		e.strict = opts.Strict
+		e.recursive = opts.Recursive
+		if opts.MaxDepth > 0 {
+			e.maxDepth = opts.MaxDepth
+		}
+	}
+	return e.expand(s, 0)
+}
+
+type expander struct {
+	repository Getter
+	strict     bool
+	recursive  bool
+	maxDepth   int
+}
+
+func (e expander) expand(s string, depth int) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$' && i+1 < len(s) && s[i+1] == '$':
+			b.WriteByte('$')
+			i++
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := closingBrace(s, i+2)
+			if end == -1 {
+				return "", fmt.Errorf("unterminated variable reference: %s", s[i:])
+			}
+			value, err := e.expandReference(s[i+2:end], depth)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+		case c == '$' && i+1 < len(s) && isNameChar(s[i+1], true):
+			end := i + 1
+			for end < len(s) && isNameChar(s[end], false) {
+				end++
+			}
+			value, err := e.lookup(s[i+1:end], depth)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// expandReference expands the content of a ${...} reference.
+func (e expander) expandReference(ref string, depth int) (string, error) {
+	name, operand, operator := ref, "", ""
+	if i := strings.Index(ref, ":"); i != -1 && i+1 < len(ref) && (ref[i+1] == '-' || ref[i+1] == '?') {
+		name, operator, operand = ref[:i], ref[i:i+2], ref[i+2:]
+	}
+	if !isName(name) {
+		return "", fmt.Errorf("invalid variable reference: ${%s}", ref)
+	}
+
+	switch operator {
+	case ":-":
+		if e.repository.Get(name) == "" {
+			return e.expand(operand, depth)
+		}
+	case ":?":
+		if e.repository.Get(name) == "" {
+			message, err := e.expand(operand, depth)
+			if err != nil {
+				return "", err
+			}
+			if message == "" {
+				message = "parameter null or not set"
+			}
+			return "", &UndefinedVariableError{Key: name, Message: message}
+		}
+	}
+	return e.lookup(name, depth)
+}
+
+func (e expander) lookup(name string, depth int) (string, error) {
+	value := e.repository.Get(name)
+	if value == "" {
+		if e.strict {
+			return "", &UndefinedVariableError{Key: name}
+		}
+		return "", nil
+	}
+	if !e.recursive {
+		return value, nil
+	}
+
+	if depth >= e.maxDepth {
+		return "", fmt.Errorf("expanding %s: maximum expansion depth (%d) exceeded", name, e.maxDepth)
+	}
+	return e.expand(value, depth+1)
+}
+
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameChar(s[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameChar(c byte, first bool) bool {
+	switch {
+	case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
+
+// closingBrace returns the index of the } closing the reference starting at start, or -1.
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// getterFunc adapts a function to the Getter interface.
+type getterFunc func(key string) string
+
+// Get ...
+func (f getterFunc) Get(key string) string {
+	return f(key)
+}
@@ -0,0 +1,68 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	repo := NewMemoryRepository([]string{
+		"HOME=/home/user",
+		"NAME=app",
+		"EMPTY=",
+		"NESTED=$HOME/$NAME",
+		"SELF=x$SELF",
+	})
+
+	tests := []struct {
+		name    string
+		s       string
+		opts    *ExpandOpts
+		want    string
+		wantErr string
+	}{
+		{name: "no references", s: "plain text", want: "plain text"},
+		{name: "simple", s: "$HOME/go", want: "/home/user/go"},
+		{name: "braces", s: "${NAME}_build", want: "app_build"},
+		{name: "undefined", s: "a${MISSING}b$EMPTY", want: "ab"},
+		{name: "default", s: "${MISSING:-fallback} ${NAME:-fallback}", want: "fallback app"},
+		{name: "nested default", s: "${MISSING:-${EMPTY:-$HOME}/go}", want: "/home/user/go"},
+		{name: "escaped dollar", s: "cost: $$HOME $$$NAME", want: "cost: $HOME $app"},
+		{name: "lone dollar", s: "$ 5 $1 $", want: "$ 5 $1 $"},
+		{name: "not recursive by default", s: "$NESTED", want: "$HOME/$NAME"},
+		{name: "recursive", s: "$NESTED", opts: &ExpandOpts{Recursive: true}, want: "/home/user/app"},
+		{
+			name:    "recursion limit",
+			s:       "$SELF",
+			opts:    &ExpandOpts{Recursive: true, MaxDepth: 3},
+			wantErr: "expanding SELF: maximum expansion depth (3) exceeded",
+		},
+		{name: "required with message", s: "${MISSING:?must be set for $NAME}", wantErr: "MISSING: must be set for app"},
+		{name: "required without message", s: "${EMPTY:?}", wantErr: "EMPTY: parameter null or not set"},
+		{name: "required defined", s: "${NAME:?must be set}", want: "app"},
+		{name: "strict", s: "$HOME/$MISSING", opts: &ExpandOpts{Strict: true}, wantErr: "undefined environment variable: MISSING"},
+		{name: "strict with default", s: "${MISSING:-ok}", opts: &ExpandOpts{Strict: true}, want: "ok"},
+		{name: "unterminated", s: "${HOME", wantErr: "unterminated variable reference: ${HOME"},
+		{name: "invalid reference", s: "${HOME:=x}", wantErr: "invalid variable reference: ${HOME:=x}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Expand(repo, tt.s, tt.opts)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("undefined variable error", func(t *testing.T) {
+		_, err := Expand(repo, "$MISSING", &ExpandOpts{Strict: true})
+
+		var undefinedErr *UndefinedVariableError
+		require.ErrorAs(t, err, &undefinedErr)
+		require.Equal(t, "MISSING", undefinedErr.Key)
+	})
+}
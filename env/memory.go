@@ -0,0 +1,223 @@
+package env
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NewMemoryRepository returns a Repository which keeps the variables in memory, without touching
+// the process environment. It starts with the "KEY=VALUE" entries of environ (like os.Environ()),
+// List returns the variables sorted by key. Safe for concurrent use.
+func NewMemoryRepository(environ []string) Repository {
+	r := &memoryRepository{values: map[string]string{}}
+	for _, entry := range environ {
+		key, value := splitEntry(entry)
+		r.values[key] = value
+	}
+	return r
+}
+
+type memoryRepository struct {
+	mux    sync.RWMutex
+	values map[string]string
+}
+
+// Get ...
+func (r *memoryRepository) Get(key string) string {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	return r.values[key]
+}
+
+// Set ...
+func (r *memoryRepository) Set(key, value string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.values[key] = value
+	return nil
+}
+
+// Unset ...
+func (r *memoryRepository) Unset(key string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	delete(r.values, key)
+	return nil
+}
+
+// List ...
+func (r *memoryRepository) List() []string {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	keys := make([]string, 0, len(r.values))
+	for key := range r.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, key+"="+r.values[key])
+	}
+	return entries
+}
+
+// LayeredRepository is a Repository on top of a parent Repository: reads fall through to the parent,
+// writes (Set and Unset) are kept in the layer and never reach the parent.
+type LayeredRepository interface {
+	Repository
+	// Snapshot returns the variables visible through the layer.
+	Snapshot() map[string]string
+	// Diff returns the changes of the layer compared to the current state of the parent.
+	Diff() Diff
+}
+
+// Diff is the difference between a LayeredRepository and its parent.
+type Diff struct {
+	// Set are the variables set in the layer with a value different from the parent's (or missing from the parent).
+	Set map[string]string
+	// Unset are the keys of the parent's variables unset in the layer, sorted.
+	Unset []string
+}
+
+// NewLayeredRepository returns a LayeredRepository on top of parent. Safe for concurrent use
+// if parent is safe for concurrent use.
+func NewLayeredRepository(parent Repository) LayeredRepository {
+	return &layeredRepository{
+		parent:  parent,
+		changes: map[string]layerChange{},
+	}
+}
+
+type layeredRepository struct {
+	parent Repository
+
+	mux     sync.RWMutex
+	changes map[string]layerChange
+}
+
+type layerChange struct {
+	value string
+	unset bool
+}
+
+// Get ...
+func (r *layeredRepository) Get(key string) string {
+	r.mux.RLock()
+	change, ok := r.changes[key]
+	r.mux.RUnlock()
+
+	if ok {
+		return change.value
+	}
+	return r.parent.Get(key)
+}
+
+// Set ...
+func (r *layeredRepository) Set(key, value string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.changes[key] = layerChange{value: value}
+	return nil
+}
+
+// Unset ...
+func (r *layeredRepository) Unset(key string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.changes[key] = layerChange{unset: true}
+	return nil
+}
+
+// List returns the parent's variables in the parent's order with the layer's changes applied,
+// followed by the variables added by the layer, sorted by key.
+func (r *layeredRepository) List() []string {
+	parentEntries := r.parent.List()
+
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	var entries []string
+	seen := map[string]bool{}
+	for _, entry := range parentEntries {
+		key, _ := splitEntry(entry)
+		seen[key] = true
+
+		change, ok := r.changes[key]
+		switch {
+		case !ok:
+			entries = append(entries, entry)
+		case !change.unset:
+			entries = append(entries, key+"="+change.value)
+		}
+	}
+
+	var added []string
+	for key, change := range r.changes {
+		if !seen[key] && !change.unset {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	for _, key := range added {
+		entries = append(entries, key+"="+r.changes[key].value)
+	}
+
+	return entries
+}
+
+// Snapshot ...
+func (r *layeredRepository) Snapshot() map[string]string {
+	snapshot := map[string]string{}
+	for _, entry := range r.List() {
+		key, value := splitEntry(entry)
+		snapshot[key] = value
+	}
+	return snapshot
+}
+
+// Diff ...
+func (r *layeredRepository) Diff() Diff {
+	parent := map[string]string{}
+	for _, entry := range r.parent.List() {
+		key, value := splitEntry(entry)
+		parent[key] = value
+	}
+
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	diff := Diff{Set: map[string]string{}}
+	for key, change := range r.changes {
+		parentValue, inParent := parent[key]
+		switch {
+		case change.unset && inParent:
+			diff.Unset = append(diff.Unset, key)
+		case !change.unset && (!inParent || parentValue != change.value):
+			diff.Set[key] = change.value
+		}
+	}
+	sort.Strings(diff.Unset)
+
+	return diff
+}
+
+// splitEntry splits a "KEY=VALUE" entry. The leading = of Windows' per-drive variables (like =C:=C:\) belongs to the key.
+func splitEntry(entry string) (string, string) {
+	start := 0
+	if strings.HasPrefix(entry, "=") {
+		start = 1
+	}
+	idx := strings.Index(entry[start:], "=")
+	if idx == -1 {
+		return entry, ""
+	}
+	return entry[:start+idx], entry[start+idx+1:]
+}
@@ -0,0 +1,54 @@
+package env
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	repo := NewMemoryRepository([]string{"B=2", "A=1=one", "=C:=C:\\"})
+
+	require.Equal(t, "1=one", repo.Get("A"))
+	require.Equal(t, "C:\\", repo.Get("=C:"))
+
+	require.NoError(t, repo.Set("D", "4"))
+	require.NoError(t, repo.Unset("B"))
+	require.Equal(t, []string{"=C:=C:\\", "A=1=one", "D=4"}, repo.List())
+	require.Equal(t, "", repo.Get("B"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = Revokable(repo, "A", "scoped")
+			_ = repo.List()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLayeredRepository(t *testing.T) {
+	parent := NewMemoryRepository([]string{"A=1", "B=2", "C=3"})
+	layer := NewLayeredRepository(parent)
+
+	require.NoError(t, layer.Set("B", "two"))
+	require.NoError(t, layer.Set("C", "3"))
+	require.NoError(t, layer.Unset("A"))
+	require.NoError(t, layer.Set("Z", "26"))
+	require.NoError(t, layer.Set("Y", "25"))
+	require.NoError(t, layer.Unset("NOT_SET"))
+
+	require.Equal(t, "", layer.Get("A"))
+	require.Equal(t, "two", layer.Get("B"))
+	require.Equal(t, []string{"B=two", "C=3", "Y=25", "Z=26"}, layer.List())
+	require.Equal(t, map[string]string{"B": "two", "C": "3", "Y": "25", "Z": "26"}, layer.Snapshot())
+	require.Equal(t, Diff{Set: map[string]string{"B": "two", "Y": "25", "Z": "26"}, Unset: []string{"A"}}, layer.Diff())
+
+	// The parent is not modified, and its later changes are visible through the layer.
+	require.Equal(t, []string{"A=1", "B=2", "C=3"}, parent.List())
+	require.NoError(t, parent.Set("D", "4"))
+	require.Equal(t, "4", layer.Get("D"))
+}
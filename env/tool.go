@@ -0,0 +1,296 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultVersionPattern matches the first dotted version number (like 1.2 or 1.2.3-beta.1) in the version output of a tool.
+var DefaultVersionPattern = regexp.MustCompile(`\d+(?:\.\d+)+(?:-[0-9A-Za-z.]+)?`)
+
+// versionProbeTimeout caps the time a tool has to print its version.
+const versionProbeTimeout = 30 * time.Second
+
+// Tool is an executable found on PATH.
+type Tool struct {
+	// Name is the name the tool was looked up with.
+	Name string
+	// Path is the path of the executable in the PATH directory.
+	Path string
+	// ResolvedPath is Path with its symlinks resolved.
+	ResolvedPath string
+	// Version is the version printed by the tool, set by ToolResolver.Resolve and ToolResolver.Require.
+	Version *Version
+}
+
+// String ...
+func (t Tool) String() string {
+	if t.Version == nil {
+		return fmt.Sprintf("%s (%s)", t.Name, t.Path)
+	}
+	return fmt.Sprintf("%s %s (%s)", t.Name, t.Version, t.Path)
+}
+
+// VersionProbe configures how the version of a tool is determined.
+type VersionProbe struct {
+	// Args make the tool print its version. Defaults to "--version".
+	Args []string
+	// Pattern finds the version in the output (stdout and stderr) of the tool, its first submatch is used
+	// if it has any, otherwise the whole match. Defaults to DefaultVersionPattern.
+	Pattern *regexp.Regexp
+}
+
+// ToolResolver finds tools on PATH and determines their versions.
+// The tools are run with the environment of the repository, and their versions are cached per process,
+// by the path of the executable and the probe. Failed probes are not cached.
+type ToolResolver interface {
+	CommandLocator
+	// Candidates returns the executables named name in the PATH directories, in PATH order.
+	// Executables resolving to the same file are returned once.
+	Candidates(name string) ([]Tool, error)
+	// Resolve returns the first candidate, the one LookPath returns, with its version.
+	Resolve(name string, probe VersionProbe) (Tool, error)
+	// Require returns the first candidate whose version satisfies constraint (see ParseVersionConstraint).
+	// A *ToolVersionError is returned if none of them does.
+	Require(name, constraint string, probe VersionProbe) (Tool, error)
+}
+
+// ToolVersionError is returned when the version of a tool does not satisfy the required constraint.
+type ToolVersionError struct {
+	// Tool is the first candidate of the tool on PATH.
+	Tool Tool
+	// Constraint is the required version constraint.
+	Constraint string
+	// Candidates are all the candidates which were checked.
+	Candidates []Tool
+}
+
+// Error ...
+func (e *ToolVersionError) Error() string {
+	return fmt.Sprintf("tool %s version %s (%s) does not satisfy %s", e.Tool.Name, e.Tool.Version, e.Tool.Path, e.Constraint)
+}
+
+type toolResolver struct {
+	repository Getter
+}
+
+// NewToolResolver returns a ToolResolver which reads PATH from repository.
+func NewToolResolver(repository Getter) ToolResolver {
+	return toolResolver{repository: repository}
+}
+
+// LookPath returns the path of the first candidate.
+func (r toolResolver) LookPath(file string) (string, error) {
+	candidates, err := r.Candidates(file)
+	if err != nil {
+		return "", err
+	}
+	return candidates[0].Path, nil
+}
+
+// Candidates ...
+func (r toolResolver) Candidates(name string) ([]Tool, error) {
+	var candidates []Tool
+	seen := map[string]bool{}
+	for _, path := range r.executablePaths(name) {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			continue
+		}
+		if resolved, err = filepath.Abs(resolved); err != nil || seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+
+		candidates = append(candidates, Tool{Name: name, Path: path, ResolvedPath: resolved})
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("tool %s not found in $PATH: %w", name, exec.ErrNotFound)
+	}
+	return candidates, nil
+}
+
+// Resolve ...
+func (r toolResolver) Resolve(name string, probe VersionProbe) (Tool, error) {
+	candidates, err := r.Candidates(name)
+	if err != nil {
+		return Tool{}, err
+	}
+
+	tool := candidates[0]
+	version, err := probeVersion(tool.Path, r.probeEnv(), probe)
+	if err != nil {
+		return Tool{}, fmt.Errorf("failed to determine the version of %s: %w", tool.Path, err)
+	}
+	tool.Version = &version
+	return tool, nil
+}
+
+// Require ...
+func (r toolResolver) Require(name, constraint string, probe VersionProbe) (Tool, error) {
+	versionConstraint, err := ParseVersionConstraint(constraint)
+	if err != nil {
+		return Tool{}, err
+	}
+
+	candidates, err := r.Candidates(name)
+	if err != nil {
+		return Tool{}, err
+	}
+
+	var probed []Tool
+	var firstErr error
+	environ := r.probeEnv()
+	for _, tool := range candidates {
+		version, err := probeVersion(tool.Path, environ, probe)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to determine the version of %s: %w", tool.Path, err)
+			}
+			continue
+		}
+		tool.Version = &version
+		if versionConstraint.Check(version) {
+			return tool, nil
+		}
+		probed = append(probed, tool)
+	}
+
+	if len(probed) == 0 {
+		return Tool{}, firstErr
+	}
+	return Tool{}, &ToolVersionError{Tool: probed[0], Constraint: constraint, Candidates: probed}
+}
+
+// executablePaths returns the paths of the executables named name in the PATH directories.
+func (r toolResolver) executablePaths(name string) []string {
+	var names []string
+	if runtime.GOOS == "windows" && filepath.Ext(name) == "" {
+		for _, ext := range filepath.SplitList(GetOrDefault(r.repository, "PATHEXT", ".com;.exe;.bat;.cmd")) {
+			names = append(names, name+strings.ToLower(ext))
+		}
+	} else {
+		names = []string{name}
+	}
+
+	if strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+		var paths []string
+		for _, n := range names {
+			if isExecutable(n) {
+				paths = append(paths, n)
+			}
+		}
+		return paths
+	}
+
+	var paths []string
+	for _, dir := range filepath.SplitList(r.repository.Get("PATH")) {
+		if dir == "" {
+			// Unix shell semantics: an empty PATH entry means the current directory.
+			dir = "."
+		}
+		for _, n := range names {
+			path := filepath.Join(dir, n)
+			if !strings.ContainsRune(path, filepath.Separator) {
+				// Keep the current directory explicit, a bare name would be looked up in the PATH again when executed.
+				path = "." + string(filepath.Separator) + path
+			}
+			if isExecutable(path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// probeEnv returns the environment of the version probes: the variables of the repository if it lists them,
+// otherwise the process environment with the PATH of the repository.
+func (r toolResolver) probeEnv() []string {
+	if lister, ok := r.repository.(interface{ List() []string }); ok {
+		return lister.List()
+	}
+	return append(os.Environ(), "PATH="+r.repository.Get("PATH"))
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode()&0o111 != 0
+}
+
+type versionCacheKey struct {
+	path    string
+	args    string
+	pattern string
+}
+
+var (
+	versionCacheMux sync.Mutex
+	versionCache    = map[versionCacheKey]Version{}
+)
+
+// probeVersion runs the tool at path (not its resolved path, as tools like busybox dispatch on argv[0]).
+func probeVersion(path string, environ []string, probe VersionProbe) (Version, error) {
+	args := probe.Args
+	if len(args) == 0 {
+		args = []string{"--version"}
+	}
+	pattern := probe.Pattern
+	if pattern == nil {
+		pattern = DefaultVersionPattern
+	}
+
+	key := versionCacheKey{path: path, args: strings.Join(args, "\x00"), pattern: pattern.String()}
+	versionCacheMux.Lock()
+	version, ok := versionCache[key]
+	versionCacheMux.Unlock()
+	if ok {
+		return version, nil
+	}
+
+	// Errors and timeouts may be transient, only the versions are cached.
+	version, err := runVersionProbe(path, environ, args, pattern)
+	if err != nil {
+		return Version{}, err
+	}
+
+	versionCacheMux.Lock()
+	versionCache[key] = version
+	versionCacheMux.Unlock()
+
+	return version, nil
+}
+
+func runVersionProbe(path string, environ []string, args []string, pattern *regexp.Regexp) (Version, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+	defer cancel()
+
+	// The exit status is ignored, some tools exit with non-zero status after printing their version.
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = environ
+	out, err := cmd.CombinedOutput()
+	if len(out) == 0 && err != nil {
+		return Version{}, err
+	}
+
+	match := pattern.FindSubmatch(out)
+	if match == nil {
+		return Version{}, fmt.Errorf("no version matching %s in output: %s", pattern, strings.TrimSpace(string(out)))
+	}
+	raw := match[0]
+	if len(match) > 1 {
+		raw = match[1]
+	}
+	return ParseVersion(string(raw))
+}
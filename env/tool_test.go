@@ -0,0 +1,164 @@
+package env
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type pathGetter string
+
+func (p pathGetter) Get(key string) string {
+	if key == "PATH" {
+		return string(p)
+	}
+	return ""
+}
+
+// writeTool creates an executable printing the given version, every run is recorded in the runs file of dir.
+func writeTool(t *testing.T, dir, name, versionOutput string) string {
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\necho run >> \"" + filepath.Join(dir, "runs") + "\"\necho '" + versionOutput + "'\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	return path
+}
+
+func countRuns(t *testing.T, dir string) int {
+	b, err := os.ReadFile(filepath.Join(dir, "runs"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0
+	}
+	require.NoError(t, err)
+	return strings.Count(string(b), "run")
+}
+
+func TestToolResolver(t *testing.T) {
+	root := t.TempDir()
+	oldDir := filepath.Join(root, "old")
+	newDir := filepath.Join(root, "new")
+	linkDir := filepath.Join(root, "link")
+	emptyDir := filepath.Join(root, "empty")
+
+	oldTool := writeTool(t, oldDir, "mytool", "mytool version 7.3.1")
+	newTool := writeTool(t, newDir, "mytool", "mytool 8.4.0 (build 1234)")
+	require.NoError(t, os.MkdirAll(linkDir, 0o755))
+	require.NoError(t, os.Symlink(oldTool, filepath.Join(linkDir, "mytool")))
+	require.NoError(t, os.MkdirAll(emptyDir, 0o755))
+
+	// The temp dir itself may be behind a symlink (like on macOS).
+	resolvedOldTool, err := filepath.EvalSymlinks(oldTool)
+	require.NoError(t, err)
+	resolvedNewTool, err := filepath.EvalSymlinks(newTool)
+	require.NoError(t, err)
+
+	resolver := NewToolResolver(pathGetter(strings.Join([]string{emptyDir, oldDir, linkDir, newDir}, string(os.PathListSeparator))))
+
+	t.Run("candidates", func(t *testing.T) {
+		candidates, err := resolver.Candidates("mytool")
+		require.NoError(t, err)
+		require.Equal(t, []Tool{
+			{Name: "mytool", Path: oldTool, ResolvedPath: resolvedOldTool},
+			{Name: "mytool", Path: newTool, ResolvedPath: resolvedNewTool},
+		}, candidates)
+
+		path, err := resolver.LookPath("mytool")
+		require.NoError(t, err)
+		require.Equal(t, oldTool, path)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := resolver.Resolve("missingtool", VersionProbe{})
+		require.ErrorIs(t, err, exec.ErrNotFound)
+	})
+
+	t.Run("resolve caches the version", func(t *testing.T) {
+		before := countRuns(t, oldDir)
+
+		tool, err := resolver.Resolve("mytool", VersionProbe{})
+		require.NoError(t, err)
+		require.Equal(t, &Version{Major: 7, Minor: 3, Patch: 1}, tool.Version)
+		require.Equal(t, "mytool 7.3.1 ("+oldTool+")", tool.String())
+
+		_, err = resolver.Resolve("mytool", VersionProbe{})
+		require.NoError(t, err)
+		require.Equal(t, before+1, countRuns(t, oldDir))
+	})
+
+	t.Run("custom probe", func(t *testing.T) {
+		tool, err := resolver.Resolve("mytool", VersionProbe{Args: []string{"-v"}, Pattern: regexp.MustCompile(`version (\d+)`)})
+		require.NoError(t, err)
+		require.Equal(t, &Version{Major: 7}, tool.Version)
+	})
+
+	t.Run("require", func(t *testing.T) {
+		tool, err := resolver.Require("mytool", ">=8.0 <9", VersionProbe{})
+		require.NoError(t, err)
+		require.Equal(t, newTool, tool.Path)
+
+		_, err = resolver.Require("mytool", ">=9", VersionProbe{})
+		var versionErr *ToolVersionError
+		require.ErrorAs(t, err, &versionErr)
+		require.Len(t, versionErr.Candidates, 2)
+		require.EqualError(t, err, "tool mytool version 7.3.1 ("+oldTool+") does not satisfy >=9")
+	})
+
+	t.Run("unparsable version", func(t *testing.T) {
+		writeTool(t, filepath.Join(root, "broken"), "brokentool", "no version here")
+		resolver := NewToolResolver(pathGetter(filepath.Join(root, "broken")))
+
+		_, err := resolver.Resolve("brokentool", VersionProbe{})
+		require.ErrorContains(t, err, "no version matching")
+
+		// Failed probes are not cached.
+		before := countRuns(t, filepath.Join(root, "broken"))
+		_, err = resolver.Resolve("brokentool", VersionProbe{})
+		require.ErrorContains(t, err, "no version matching")
+		require.Equal(t, before+1, countRuns(t, filepath.Join(root, "broken")))
+	})
+
+	t.Run("argv[0] dispatch", func(t *testing.T) {
+		multiDir := filepath.Join(root, "multi")
+		require.NoError(t, os.MkdirAll(multiDir, 0o755))
+		script := "#!/bin/sh\ncase \"$0\" in\n*multi++) echo 2.0.0 ;;\n*) echo 1.0.0 ;;\nesac\n"
+		require.NoError(t, os.WriteFile(filepath.Join(multiDir, "multi"), []byte(script), 0o755))
+		require.NoError(t, os.Symlink("multi", filepath.Join(multiDir, "multi++")))
+		resolver := NewToolResolver(pathGetter(multiDir))
+
+		tool, err := resolver.Resolve("multi++", VersionProbe{})
+		require.NoError(t, err)
+		require.Equal(t, &Version{Major: 2}, tool.Version)
+	})
+
+	t.Run("repository environment", func(t *testing.T) {
+		envDir := filepath.Join(root, "env")
+		require.NoError(t, os.MkdirAll(envDir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(envDir, "envtool"), []byte("#!/bin/sh\necho \"envtool $ENVTOOL_VERSION\"\n"), 0o755))
+		resolver := NewToolResolver(NewMemoryRepository([]string{"PATH=" + envDir, "ENVTOOL_VERSION=3.1.4"}))
+
+		tool, err := resolver.Resolve("envtool", VersionProbe{})
+		require.NoError(t, err)
+		require.Equal(t, &Version{Major: 3, Minor: 1, Patch: 4}, tool.Version)
+	})
+	t.Run("empty PATH entry", func(t *testing.T) {
+		cwdDir := filepath.Join(root, "cwd")
+		writeTool(t, cwdDir, "cwdtool", "cwdtool 1.2.3")
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		require.NoError(t, os.Chdir(cwdDir))
+		defer func() { require.NoError(t, os.Chdir(wd)) }()
+
+		resolver := NewToolResolver(pathGetter(string(os.PathListSeparator) + emptyDir))
+
+		tool, err := resolver.Resolve("cwdtool", VersionProbe{})
+		require.NoError(t, err)
+		require.Equal(t, "."+string(filepath.Separator)+"cwdtool", tool.Path)
+		require.Equal(t, &Version{Major: 1, Minor: 2, Patch: 3}, tool.Version)
+	})
+}
@@ -0,0 +1,226 @@
+package env
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version, the missing components of a shorter version (like 8 or 8.1) are 0.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease string
+}
+
+// ParseVersion parses versions like 1.2.3, v1.2, 8 or 1.2.3-beta.1, build metadata (+...) is ignored.
+// Components after the patch version (like the 4 of 1.2.3.4) are ignored as well.
+func ParseVersion(s string) (Version, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i != -1 {
+		s = s[:i]
+	}
+
+	var v Version
+	if i := strings.IndexByte(s, '-'); i != -1 {
+		v.PreRelease = s[i+1:]
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	components := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		if i >= len(components) {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version: %q", raw)
+		}
+		*components[i] = n
+	}
+
+	return v, nil
+}
+
+// String ...
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or greater than other.
+// A pre-release version is lower than the release version, pre-releases are compared by their dot separated
+// identifiers like in semver: numerically if both are numeric (beta.2 < beta.10), otherwise lexically.
+func (v Version) Compare(other Version) int {
+	for _, c := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if c[0] != c[1] {
+			if c[0] < c[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case v.PreRelease == other.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case other.PreRelease == "":
+		return -1
+	default:
+		return comparePreRelease(v.PreRelease, other.PreRelease)
+	}
+}
+
+// comparePreRelease compares the identifiers of two pre-releases: numeric identifiers are lower than
+// alphanumeric ones, and a pre-release with more identifiers is greater if the preceding ones are equal.
+func comparePreRelease(a, b string) int {
+	aIDs, bIDs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		aNum, aErr := strconv.ParseUint(aIDs[i], 10, 64)
+		bNum, bErr := strconv.ParseUint(bIDs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNum != bNum {
+				return compareInts(aNum, bNum)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case aIDs[i] != bIDs[i]:
+			return strings.Compare(aIDs[i], bIDs[i])
+		}
+	}
+	return compareInts(uint64(len(aIDs)), uint64(len(bIDs)))
+}
+
+func compareInts(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// VersionConstraint is a set of version comparisons, like ">=8.0 <9" or "1.2.3 || >=2".
+// The space separated comparisons must all be satisfied, alternatives are separated by "||".
+// The operator may be followed by spaces, like ">= 8.0".
+// The supported operators are =, !=, >, >=, < and <=, a version without an operator must be equal.
+type VersionConstraint struct {
+	raw          string
+	alternatives [][]versionComparison
+}
+
+type versionComparison struct {
+	operator string
+	version  Version
+}
+
+// ParseVersionConstraint ...
+func ParseVersionConstraint(s string) (*VersionConstraint, error) {
+	c := &VersionConstraint{raw: s}
+	for _, alternative := range strings.Split(s, "||") {
+		var comparisons []versionComparison
+		fields := strings.Fields(alternative)
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			if isVersionOperator(field) && i+1 < len(fields) {
+				// The operator is separated from its version.
+				i++
+				field += fields[i]
+			}
+
+			comparison, err := parseVersionComparison(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+			}
+			comparisons = append(comparisons, comparison)
+		}
+		if len(comparisons) == 0 {
+			return nil, fmt.Errorf("invalid version constraint %q: empty constraint", s)
+		}
+		c.alternatives = append(c.alternatives, comparisons)
+	}
+	return c, nil
+}
+
+var versionOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+func isVersionOperator(s string) bool {
+	for _, op := range versionOperators {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+func parseVersionComparison(s string) (versionComparison, error) {
+	operator := ""
+	for _, op := range versionOperators {
+		if strings.HasPrefix(s, op) {
+			operator = op
+			break
+		}
+	}
+
+	version, err := ParseVersion(strings.TrimPrefix(s, operator))
+	if err != nil {
+		return versionComparison{}, err
+	}
+	if operator == "" || operator == "==" {
+		operator = "="
+	}
+	return versionComparison{operator: operator, version: version}, nil
+}
+
+// Check reports whether v satisfies the constraint.
+func (c *VersionConstraint) Check(v Version) bool {
+	for _, comparisons := range c.alternatives {
+		satisfied := true
+		for _, comparison := range comparisons {
+			if !comparison.check(v) {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the constraint as it was parsed.
+func (c *VersionConstraint) String() string {
+	return c.raw
+}
+
+func (c versionComparison) check(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.operator {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Version
+		wantErr bool
+	}{
+		{input: "1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{input: "v8.1", want: Version{Major: 8, Minor: 1}},
+		{input: "17", want: Version{Major: 17}},
+		{input: "1.2.3.4", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{input: "2.0.0-beta.1+build.5", want: Version{Major: 2, PreRelease: "beta.1"}},
+		{input: "1.x", wantErr: true},
+		{input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseVersion(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{constraint: ">=8.0 <9", version: "8.4.1", want: true},
+		{constraint: ">=8.0 <9", version: "9.0", want: false},
+		{constraint: ">=8.0 <9", version: "7.9.9", want: false},
+		{constraint: "1.2.3", version: "1.2.3", want: true},
+		{constraint: "!=1.2.3", version: "1.2.3", want: false},
+		{constraint: "<=1.2 || >=3", version: "3.1", want: true},
+		{constraint: "<=1.2 || >=3", version: "2.0", want: false},
+		{constraint: ">=2.0.0", version: "2.0.0-rc.1", want: false},
+		{constraint: ">2.0.0-alpha", version: "2.0.0-beta", want: true},
+		{constraint: ">= 8.0 < 9", version: "8.4.1", want: true},
+		{constraint: ">= 8.0 < 9", version: "9.0", want: false},
+		{constraint: "<= 1.2 || == 3.1", version: "3.1", want: true},
+		{constraint: ">2.0.0-beta.2", version: "2.0.0-beta.10", want: true},
+		{constraint: "<2.0.0-beta.10", version: "2.0.0-beta.9", want: true},
+		{constraint: ">2.0.0-beta.1", version: "2.0.0-beta", want: false},
+		{constraint: ">2.0.0-alpha.1", version: "2.0.0-alpha.beta", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			c, err := ParseVersionConstraint(tt.constraint)
+			require.NoError(t, err)
+			v, err := ParseVersion(tt.version)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, c.Check(v))
+		})
+	}
+
+	_, err := ParseVersionConstraint(">=8.0 ||")
+	require.EqualError(t, err, `invalid version constraint ">=8.0 ||": empty constraint`)
+	_, err = ParseVersionConstraint(">=")
+	require.EqualError(t, err, `invalid version constraint ">=": invalid version: ""`)
+	_, err = ParseVersionConstraint(">=eight")
+	require.EqualError(t, err, `invalid version constraint ">=eight": invalid version: "eight"`)
+}
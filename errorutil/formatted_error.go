@@ -7,11 +7,17 @@ import (
 	"github.com/bitrise-io/go-utils/v2/command"
 )
 
-// FormattedError ...
+// FormattedError renders err and the errors it wraps as an indented list, one level per wrapped error.
+// Errors joining multiple errors (like errors.Join and command.GroupError) are rendered as a tree,
+// with each of the joined errors indented below the joining error's own message.
 func FormattedError(err error) string {
+	return formatError(err, 0)
+}
+
+func formatError(err error, level int) string {
 	var formatted string
 
-	i := -1
+	i := level - 1
 	for {
 		i++
 
@@ -20,6 +26,10 @@ func FormattedError(err error) string {
 			err = commandExitStatusError.Reason()
 		}
 
+		if multiErr, ok := err.(interface{ Unwrap() []error }); ok {
+			return appendErrorTree(formatted, err, multiErr.Unwrap(), i)
+		}
+
 		reason := err.Error()
 		if err = errors.Unwrap(err); err == nil {
 			formatted = appendError(formatted, reason, i, true)
@@ -34,8 +44,37 @@ func FormattedError(err error) string {
 	}
 }
 
+// appendErrorTree appends the own message of err (if any) and the formatted joined errors one level deeper.
+func appendErrorTree(errorMessage string, err error, joined []error, level int) string {
+	var joinedErrors []error
+	var joinedMessages []string
+	for _, e := range joined {
+		if e != nil {
+			joinedErrors = append(joinedErrors, e)
+			joinedMessages = append(joinedMessages, e.Error())
+		}
+	}
+
+	reason := strings.TrimSuffix(err.Error(), strings.Join(joinedMessages, "\n"))
+	reason = strings.TrimRight(reason, " \n")
+	reason = strings.TrimSuffix(reason, ":")
+
+	if reason != "" {
+		errorMessage = appendError(errorMessage, reason, level, len(joinedErrors) == 0)
+		level++
+	}
+
+	for _, e := range joinedErrors {
+		if errorMessage != "" {
+			errorMessage += "\n"
+		}
+		errorMessage += formatError(e, level)
+	}
+	return errorMessage
+}
+
 func appendError(errorMessage, reason string, i int, last bool) string {
-	if i == 0 {
+	if errorMessage == "" {
 		errorMessage = indentedReason(reason, i)
 	} else {
 		errorMessage += "\n"
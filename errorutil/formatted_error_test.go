@@ -54,6 +54,16 @@ func TestFormattedError(t *testing.T) {
 				return err
 			}, wantFormattedError: "fourth layer also failed: third layer also failed: second layer also failed: the magic has failed",
 		},
+		{
+			name: "Joined errors",
+			error: func() error {
+				err := errors.Join(errors.New("first failed"), fmt.Errorf("second failed: %w", errors.New("the magic has failed")))
+				return fmt.Errorf("wrapped: %w", err)
+			}, wantFormattedError: `wrapped:
+  first failed
+  second failed:
+    the magic has failed`,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -144,6 +154,40 @@ Error: fourth error`,
   Error: third error
   Error: fourth error`,
 		},
+		{
+			name: "pipeline stage error",
+			cmdFn: func() error {
+				return command.Pipeline(
+					commandFactory.Create("echo", []string{"test"}, nil),
+					commandFactory.Create("bash", []string{"../command/testdata/exit_42.sh"}, nil),
+				).Run()
+			},
+			wantErr: `pipeline stage 2 failed (echo "test" | bash "../command/testdata/exit_42.sh"): command failed with exit status 42 (bash "../command/testdata/exit_42.sh"): check the command's output for details`,
+			wantMsg: `pipeline stage 2 failed (echo "test" | bash "../command/testdata/exit_42.sh"):
+  command failed with exit status 42 (bash "../command/testdata/exit_42.sh"):
+    check the command's output for details`,
+		},
+		{
+			name: "group error",
+			cmdFn: func() error {
+				group := command.NewGroup(command.GroupOpts{Concurrency: 1})
+				group.Add("exit", commandFactory.Create("bash", []string{"../command/testdata/exit_42.sh"}, nil))
+				group.Add("ok", commandFactory.Create("true", nil, nil))
+				group.Add("missing", commandFactory.Create("__notfoundinpath", nil, nil))
+				return group.Run()
+			},
+			wantErr: `2 of 3 commands failed:
+exit: command failed with exit status 42 (bash "../command/testdata/exit_42.sh"): check the command's output for details
+missing: executing command failed (__notfoundinpath): exec: "__notfoundinpath": executable file not found in $PATH`,
+			wantMsg: `2 of 3 commands failed:
+  exit:
+    command failed with exit status 42 (bash "../command/testdata/exit_42.sh"):
+      check the command's output for details
+  missing:
+    executing command failed (__notfoundinpath):
+      exec: "__notfoundinpath":
+        executable file not found in $PATH`,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -68,6 +68,18 @@ func (m *mockLogger) TDebugf(format string, v ...interface{}) {}
 func (m *mockLogger) TErrorf(format string, v ...interface{}) {}
 func (m *mockLogger) Println()                                {}
 func (m *mockLogger) EnableDebugLog(enable bool)              {}
+func (m *mockLogger) With(keyvals ...interface{}) log.Logger  { return m }
+func (m *mockLogger) Info(msg string, keyvals ...interface{}) {}
+func (m *mockLogger) Warn(msg string, keyvals ...interface{}) {
+	m.warnings = append(m.warnings, msg)
+}
+func (m *mockLogger) Print(msg string, keyvals ...interface{}) {}
+func (m *mockLogger) Done(msg string, keyvals ...interface{})  {}
+func (m *mockLogger) Debug(msg string, keyvals ...interface{}) {}
+func (m *mockLogger) Error(msg string, keyvals ...interface{}) {}
+func (m *mockLogger) BeginSection(title string)                {}
+func (m *mockLogger) EndSection()                              {}
+func (m *mockLogger) Section(title string, fn func())          { fn() }
 
 func TestDownloader_Get_Success(t *testing.T) {
 	client := new(mockHTTPClient)
@@ -24,6 +24,9 @@ const (
 type ColorFunc func(a ...interface{}) string
 
 func addColor(color Color, msg string) string {
+	if !Enabled() {
+		return msg
+	}
 	return string(color) + msg + string(resetColor)
 }
 
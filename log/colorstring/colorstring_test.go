@@ -8,6 +8,8 @@ import (
 )
 
 func TestAddColor(t *testing.T) {
+	setMode(t, ModeAlways)
+
 	/*
 	  blackColor   Color = "\x1b[30;1m"
 	  resetColor   Color = "\x1b[0m"
@@ -22,6 +24,8 @@ func TestAddColor(t *testing.T) {
 }
 
 func TestBlack(t *testing.T) {
+	setMode(t, ModeAlways)
+
 	t.Log("Simple string can be blacked")
 	{
 		desiredColored := "\x1b[30;1m" + "test" + "\x1b[0m"
@@ -38,6 +42,8 @@ func TestBlack(t *testing.T) {
 }
 
 func TestBlackf(t *testing.T) {
+	setMode(t, ModeAlways)
+
 	t.Log("Simple format can be blacked")
 	{
 		desiredColored := "\x1b[30;1m" + fmt.Sprintf("Hello %s", "bitrise") + "\x1b[0m"
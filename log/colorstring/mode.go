@@ -0,0 +1,98 @@
+package colorstring
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Mode decides whether colors are used.
+type Mode int
+
+const (
+	// ModeAlways always uses colors, even if NO_COLOR is set.
+	ModeAlways Mode = iota
+	// ModeAuto uses colors if the output is a terminal, unless the environment says otherwise (see ShouldColor).
+	ModeAuto
+	// ModeNever never uses colors.
+	ModeNever
+)
+
+// String ...
+func (m Mode) String() string {
+	switch m {
+	case ModeAlways:
+		return "always"
+	case ModeAuto:
+		return "auto"
+	case ModeNever:
+		return "never"
+	}
+	return "unknown"
+}
+
+// ParseMode parses the "auto", "always" and "never" modes, like the value of a --color flag.
+func ParseMode(s string) (Mode, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "auto":
+		return ModeAuto, true
+	case "always":
+		return ModeAlways, true
+	case "never":
+		return ModeNever, true
+	}
+	return ModeAlways, false
+}
+
+// ShouldColor reports whether output written to w should be colored in mode, lookup returns the value
+// of an environment variable (like os.Getenv). In ModeAuto:
+//   - NO_COLOR set to any non-empty value disables the colors (https://no-color.org),
+//   - otherwise FORCE_COLOR set to a non-empty value (other than 0 and false) enables them,
+//   - otherwise TERM=dumb disables them,
+//   - otherwise they are used if w is a terminal.
+func ShouldColor(mode Mode, w io.Writer, lookup func(key string) string) bool {
+	switch mode {
+	case ModeAlways:
+		return true
+	case ModeNever:
+		return false
+	}
+
+	if lookup("NO_COLOR") != "" {
+		return false
+	}
+	if forceColor := lookup("FORCE_COLOR"); forceColor != "" && forceColor != "0" && forceColor != "false" {
+		return true
+	}
+	if lookup("TERM") == "dumb" {
+		return false
+	}
+
+	f, ok := w.(interface{ Fd() uintptr })
+	return ok && isTerminal(f)
+}
+
+// DefaultEnabled reports whether colors are used when no mode is set: unless NO_COLOR is set to a non-empty value
+// or TERM is dumb, lookup returns the value of an environment variable (like os.Getenv).
+func DefaultEnabled(lookup func(key string) string) bool {
+	return lookup("NO_COLOR") == "" && lookup("TERM") != "dumb"
+}
+
+var colorsEnabled atomic.Bool
+
+func init() {
+	colorsEnabled.Store(DefaultEnabled(os.Getenv))
+}
+
+// SetMode sets the mode of the color helpers of the package (like Red and Bluef), ModeAuto checks os.Stdout
+// and the process environment. The loggers of the log package have their own mode (see log.WithColorMode).
+// Without SetMode the helpers add colors according to DefaultEnabled.
+func SetMode(mode Mode) {
+	colorsEnabled.Store(ShouldColor(mode, os.Stdout, os.Getenv))
+}
+
+// Enabled reports whether the color helpers of the package add colors, based on the mode set by SetMode.
+func Enabled() bool {
+	return colorsEnabled.Load()
+}
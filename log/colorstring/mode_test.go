@@ -0,0 +1,97 @@
+package colorstring
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldColor(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, devNull.Close()) }()
+
+	tests := []struct {
+		name    string
+		mode    Mode
+		environ []string
+		want    bool
+	}{
+		{name: "always", mode: ModeAlways, environ: []string{"NO_COLOR=1"}, want: true},
+		{name: "never", mode: ModeNever, environ: []string{"FORCE_COLOR=1"}, want: false},
+		{name: "auto, not a terminal", mode: ModeAuto, want: false},
+		{name: "auto, FORCE_COLOR", mode: ModeAuto, environ: []string{"FORCE_COLOR=1"}, want: true},
+		{name: "auto, FORCE_COLOR=0", mode: ModeAuto, environ: []string{"FORCE_COLOR=0"}, want: false},
+		{name: "auto, NO_COLOR wins", mode: ModeAuto, environ: []string{"NO_COLOR=true", "FORCE_COLOR=1"}, want: false},
+		{name: "auto, TERM=dumb", mode: ModeAuto, environ: []string{"TERM=dumb"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := lookupFunc(tt.environ)
+			require.Equal(t, tt.want, ShouldColor(tt.mode, devNull, lookup))
+			require.Equal(t, tt.want, ShouldColor(tt.mode, &bytes.Buffer{}, lookup))
+		})
+	}
+}
+
+func TestDefaultEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		want    bool
+	}{
+		{name: "no variables", want: true},
+		{name: "FORCE_COLOR", environ: []string{"FORCE_COLOR=1"}, want: true},
+		{name: "NO_COLOR", environ: []string{"NO_COLOR=1"}, want: false},
+		{name: "TERM=dumb", environ: []string{"TERM=dumb"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, DefaultEnabled(lookupFunc(tt.environ)))
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, mode := range []Mode{ModeAlways, ModeAuto, ModeNever} {
+		parsed, ok := ParseMode(mode.String())
+		require.True(t, ok)
+		require.Equal(t, mode, parsed)
+	}
+
+	_, ok := ParseMode("sometimes")
+	require.False(t, ok)
+}
+
+func TestSetMode(t *testing.T) {
+	setMode(t, ModeNever)
+	require.False(t, Enabled())
+	require.Equal(t, "test", Red("test"))
+	require.Equal(t, "test 1", Greenf("test %d", 1))
+
+	SetMode(ModeAlways)
+	require.True(t, Enabled())
+	require.Equal(t, "\x1b[31;1mtest\x1b[0m", Red("test"))
+}
+
+// lookupFunc returns a lookup function over environ, a list of KEY=value pairs.
+func lookupFunc(environ []string) func(key string) string {
+	return func(key string) string {
+		for _, kv := range environ {
+			if k, v, _ := strings.Cut(kv, "="); k == key {
+				return v
+			}
+		}
+		return ""
+	}
+}
+
+// setMode sets the mode of the color helpers for the duration of the test, the previous state is restored afterwards.
+func setMode(t *testing.T, mode Mode) {
+	enabled := colorsEnabled.Load()
+	t.Cleanup(func() { colorsEnabled.Store(enabled) })
+	SetMode(mode)
+}
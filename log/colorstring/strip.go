@@ -0,0 +1,117 @@
+package colorstring
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// Strip removes the ANSI escape sequences (like colors) from s.
+func Strip(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+
+	var b strings.Builder
+	var st stripState
+	for i := 0; i < len(s); i++ {
+		if st.next(s[i]) {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// NewStripWriter returns a writer which removes the ANSI escape sequences (like colors) from the output
+// written to w, for example to write colored logs to a file. Escape sequences split between writes are removed as well.
+// The writer is safe for concurrent use.
+func NewStripWriter(w io.Writer) io.Writer {
+	return &stripWriter{w: w}
+}
+
+type stripWriter struct {
+	mux   sync.Mutex
+	w     io.Writer
+	state stripState
+	buf   []byte
+}
+
+// Write ...
+func (s *stripWriter) Write(p []byte) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.buf = s.buf[:0]
+	for _, c := range p {
+		if s.state.next(c) {
+			s.buf = append(s.buf, c)
+		}
+	}
+
+	if len(s.buf) > 0 {
+		if _, err := s.w.Write(s.buf); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// stripState is the state of an ANSI escape sequence parser.
+type stripState int
+
+const (
+	stateText stripState = iota
+	stateEscape
+	stateEscapeIntermediate
+	stateCSI
+	stateOSC
+	stateOSCEscape
+)
+
+// next moves the parser to the state after c and reports whether c is text, outside of escape sequences.
+func (s *stripState) next(c byte) bool {
+	switch *s {
+	case stateEscape:
+		switch c {
+		case '[':
+			*s = stateCSI
+		case ']':
+			*s = stateOSC
+		default:
+			if c >= 0x20 && c <= 0x2f {
+				// Intermediate bytes, like the ( of the character set selection ESC ( B.
+				*s = stateEscapeIntermediate
+			} else {
+				// Two-byte sequences, like ESC c.
+				*s = stateText
+			}
+		}
+	case stateEscapeIntermediate:
+		// More intermediate bytes until the final byte.
+		if c < 0x20 || c > 0x2f {
+			*s = stateText
+		}
+	case stateCSI:
+		// Parameter and intermediate bytes until the final byte (0x40-0x7E).
+		if c >= 0x40 && c <= 0x7e {
+			*s = stateText
+		}
+	case stateOSC:
+		// Operating system commands (like hyperlinks) end with BEL or ESC \.
+		switch c {
+		case '\a':
+			*s = stateText
+		case 0x1b:
+			*s = stateOSCEscape
+		}
+	case stateOSCEscape:
+		*s = stateText
+	default:
+		if c == 0x1b {
+			*s = stateEscape
+			return false
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package colorstring
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrip(t *testing.T) {
+	require.Equal(t, "plain", Strip("plain"))
+	require.Equal(t, "error: failed", Strip(Red("error:")+" "+Yellow("failed")))
+	require.Equal(t, "link and clear", Strip("\x1b]8;;https://bitrise.io\x1b\\link\x1b]8;;\a and \x1b[2Kclear"))
+	require.Equal(t, "reset charset", Strip("reset\x1b(B charset\x1b)0"))
+	require.Equal(t, "keypad", Strip("\x1b=key\x1b#8pad"))
+}
+
+func TestStripWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := NewStripWriter(&b)
+
+	colored := Blue("info") + " " + Magenta("debug") + "\n"
+	for i := 0; i < len(colored); i++ {
+		// Byte by byte, to split the escape sequences between writes.
+		n, err := w.Write([]byte{colored[i]})
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	}
+	require.Equal(t, "info debug\n", b.String())
+}
+
+func TestStripWriter_Concurrent(t *testing.T) {
+	var b bytes.Buffer
+	w := NewStripWriter(&b)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := w.Write([]byte("\x1b[31;1mline\x1b[0m\n"))
+				require.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, strings.Repeat("line\n", 1000), b.String())
+}
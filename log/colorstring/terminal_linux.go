@@ -0,0 +1,8 @@
+package colorstring
+
+import "golang.org/x/sys/unix"
+
+func isTerminal(f interface{ Fd() uintptr }) bool {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), unix.TCGETS)
+	return err == nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package colorstring
+
+import "os"
+
+// isTerminal falls back to checking for a character device, without the termios ioctl.
+func isTerminal(f interface{ Fd() uintptr }) bool {
+	file, ok := f.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,150 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const badKey = "!BADKEY"
+
+// Field is a key-value pair attached to a log entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Entry is a log message with its metadata, formatted by an Encoder.
+type Entry struct {
+	Time     time.Time
+	Severity Severity
+	Message  string
+	Fields   []Field
+	// Timestamped is set for the messages of the T-prefixed methods (like TInfof).
+	Timestamped bool
+}
+
+// Encoder formats log entries, one line per entry (without the line ending).
+type Encoder interface {
+	Encode(entry Entry) string
+}
+
+// HumanEncoder formats the entries the way the build log shows them: the message colored by its severity,
+// followed by the fields as key=value pairs.
+type HumanEncoder struct {
+	// TimestampLayout is the layout of the timestamp of timestamped entries, defaults to "15:04:05".
+	TimestampLayout string
+	// Prefix is added to each line, before the timestamp.
+	Prefix string
+	// NoColor disables the colors.
+	NoColor bool
+}
+
+// Encode ...
+func (e HumanEncoder) Encode(entry Entry) string {
+	message := entry.Message
+	if !e.NoColor {
+		message = severityColorFuncMap[entry.Severity]("%s", entry.Message)
+	}
+	if entry.Timestamped {
+		layout := e.TimestampLayout
+		if layout == "" {
+			layout = defaultTimeStampLayout
+		}
+		message = fmt.Sprintf("[%s] %s", entry.Time.Format(layout), message)
+	}
+	message = e.Prefix + message
+
+	for _, field := range entry.Fields {
+		message += " " + field.Key + "=" + humanValue(field.Value)
+	}
+	return message
+}
+
+func humanValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case error:
+		s = v.Error()
+	case time.Duration:
+		s = v.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// JSONEncoder formats the entries as JSON objects, one per line, with the time, level and msg keys
+// followed by the fields:
+//
+//	{"time":"2024-01-02T15:04:05.123Z","level":"info","msg":"Cloning repository","step":"git-clone"}
+type JSONEncoder struct{}
+
+// Encode ...
+func (JSONEncoder) Encode(entry Entry) string {
+	var b bytes.Buffer
+	b.WriteString(`{"time":`)
+	writeJSONValue(&b, entry.Time.Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSONValue(&b, entry.Severity.String())
+	b.WriteString(`,"msg":`)
+	writeJSONValue(&b, entry.Message)
+	for _, field := range entry.Fields {
+		b.WriteByte(',')
+		writeJSONValue(&b, field.Key)
+		b.WriteByte(':')
+		writeJSONValue(&b, jsonValue(field.Value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		if _, ok := value.(json.Marshaler); !ok {
+			return v.String()
+		}
+	}
+	return value
+}
+
+func writeJSONValue(b *bytes.Buffer, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	b.Write(data)
+}
+
+// fieldsFromKeyvals pairs up alternating keys and values. A non-string key, or a key without a value
+// is logged with the !BADKEY key.
+func fieldsFromKeyvals(keyvals []interface{}) []Field {
+	fields := make([]Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i++ {
+		if field, ok := keyvals[i].(Field); ok {
+			fields = append(fields, field)
+			continue
+		}
+
+		key, ok := keyvals[i].(string)
+		if !ok || i+1 == len(keyvals) {
+			fields = append(fields, Field{Key: badKey, Value: keyvals[i]})
+			continue
+		}
+		fields = append(fields, Field{Key: key, Value: keyvals[i+1]})
+		i++
+	}
+	return fields
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log/colorstring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Structured(t *testing.T) {
+	t.Run("human encoder", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := NewLogger(WithOutput(&b), WithPrefix("> "), WithColorMode(colorstring.ModeAlways))
+		stepLogger := logger.With("step", "git-clone")
+
+		stepLogger.Info("Cloning repository", "url", "https://github.com/bitrise-io/go-utils", "depth", 1)
+		stepLogger.Error("Clone failed", "error", errors.New("exit status 128"), "took", 1500*time.Millisecond)
+		logger.Print("Plain", "message", "", "odd")
+		logger.Debug("Hidden")
+
+		require.Equal(t, "> \x1b[34;1mCloning repository\x1b[0m step=git-clone url=https://github.com/bitrise-io/go-utils depth=1\n"+
+			"> \x1b[31;1mClone failed\x1b[0m step=git-clone error=\"exit status 128\" took=1.5s\n"+
+			"> Plain message=\"\" !BADKEY=odd\n", b.String())
+	})
+
+	t.Run("printf methods keep the fields of With", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := NewLogger(WithOutput(&b), WithTimestampLayout("15-04-05"), WithColorMode(colorstring.ModeAlways)).With(Field{Key: "step", Value: "deploy"})
+		logger.TDonef("Deployed %d apps", 2)
+
+		re := regexp.MustCompile(`^\[.+-.+-.+] \x1b\[32;1mDeployed 2 apps\x1b\[0m step=deploy\n$`)
+		require.True(t, re.MatchString(b.String()), b.String())
+	})
+
+	t.Run("json encoder", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := NewLogger(WithOutput(&b), WithEncoder(JSONEncoder{}), WithDebugLog(true))
+		logger.With("step", "git-clone").Debug("Cloning \"repository\"", "depth", 1, "error", errors.New("timeout"), "took", time.Second)
+		logger.Warnf("Retrying in %ds", 5)
+
+		lines := bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(lines[0], &entry))
+		_, err := time.Parse(time.RFC3339Nano, entry["time"].(string))
+		require.NoError(t, err)
+		delete(entry, "time")
+		require.Equal(t, map[string]interface{}{
+			"level": "debug",
+			"msg":   "Cloning \"repository\"",
+			"step":  "git-clone",
+			"depth": float64(1),
+			"error": "timeout",
+			"took":  "1s",
+		}, entry)
+		require.Regexp(t, `^\{"time":"[^"]+","level":"debug","msg":"Cloning \\"repository\\"","step":"git-clone","depth":1,"error":"timeout","took":"1s"\}$`, string(lines[0]))
+
+		require.Regexp(t, `^\{"time":"[^"]+","level":"warn","msg":"Retrying in 5s"\}$`, string(lines[1]))
+	})
+}
+
+func TestHumanEncoder(t *testing.T) {
+	entry := Entry{
+		Time:        time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Severity:    NormalSeverity,
+		Message:     "message",
+		Fields:      []Field{{Key: "path", Value: "/tmp/with space"}, {Key: "ok", Value: true}},
+		Timestamped: true,
+	}
+	require.Equal(t, `[15:04:05] message path="/tmp/with space" ok=true`, HumanEncoder{}.Encode(entry))
+}
@@ -4,11 +4,19 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log/colorstring"
 )
 
 // Logger interface designed to provide only the necessary functionality used by our tooling.
 // The lack of in-line printing is intentional.
+//
+// Besides the printf-style methods, the structured methods (like Info) take a message
+// and alternating keys and values (or Field values):
+//
+//	logger.With("step", "git-clone").Info("Cloning repository", "url", url, "depth", 1)
 type Logger interface {
 	Infof(format string, v ...interface{})
 	Warnf(format string, v ...interface{})
@@ -24,6 +32,23 @@ type Logger interface {
 	TErrorf(format string, v ...interface{})
 	Println()
 	EnableDebugLog(enable bool)
+
+	// With returns a Logger which adds the given key-value pairs to each entry.
+	With(keyvals ...interface{}) Logger
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Print(msg string, keyvals ...interface{})
+	Done(msg string, keyvals ...interface{})
+	Debug(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+
+	// BeginSection starts a section titled title, the lines logged until the matching EndSection are indented.
+	// The markers of the sections are printed by the SectionFormatter of the Logger.
+	BeginSection(title string)
+	// EndSection ends the innermost section, printing its elapsed time.
+	EndSection()
+	// Section runs fn in a section titled title.
+	Section(title string, fn func())
 }
 
 const defaultTimeStampLayout = "15:04:05"
@@ -36,6 +61,12 @@ type logger struct {
 	timestampLayout string
 	stdout          io.Writer
 	prefix          string
+	encoder         Encoder
+	fields          []Field
+	sections        *sections
+	sinks           *sinkSet
+	colorMode       *colorstring.Mode
+	noColor         bool
 }
 
 // NewLogger ...
@@ -44,14 +75,24 @@ func NewLogger(options ...LoggerOptions) Logger {
 		enableDebugLog:  false,
 		timestampLayout: defaultTimeStampLayout,
 		stdout:          os.Stdout,
+		sections:        newSections(),
 	}
 
 	for _, option := range options {
 		option(l)
 	}
+	l.noColor = !l.shouldColor(l.stdout)
 	return l
 }
 
+// shouldColor reports whether the messages written to w are colored, according to WithColorMode.
+func (l *logger) shouldColor(w io.Writer) bool {
+	if l.colorMode == nil {
+		return colorstring.DefaultEnabled(os.Getenv)
+	}
+	return colorstring.ShouldColor(*l.colorMode, w, os.Getenv)
+}
+
 // WithDebugLog ...
 func WithDebugLog(enable bool) LoggerOptions {
 	return func(l *logger) {
@@ -81,6 +122,25 @@ func WithPrefix(prefix string) LoggerOptions {
 	}
 }
 
+// WithColorMode sets whether the messages are colored by severity. By default they are colored,
+// unless the NO_COLOR or TERM=dumb environment variables disable the colors (see colorstring.DefaultEnabled).
+// colorstring.ModeAuto colors the messages only if the output (see WithOutput) is a terminal,
+// and honors the NO_COLOR, FORCE_COLOR and TERM environment variables (see colorstring.ShouldColor).
+// The mode of the Logger does not depend on the mode of the colorstring helpers (see colorstring.SetMode).
+func WithColorMode(mode colorstring.Mode) LoggerOptions {
+	return func(l *logger) {
+		l.colorMode = &mode
+	}
+}
+
+// WithEncoder sets the format of the log lines, defaults to HumanEncoder configured by
+// WithTimestampLayout, WithPrefix and WithColorMode.
+func WithEncoder(encoder Encoder) LoggerOptions {
+	return func(l *logger) {
+		l.encoder = encoder
+	}
+}
+
 // EnableDebugLog ...
 // Deprecated: use WithDebugLog option instead
 func (l *logger) EnableDebugLog(enable bool) {
@@ -89,100 +149,150 @@ func (l *logger) EnableDebugLog(enable bool) {
 
 // Infof ...
 func (l *logger) Infof(format string, v ...interface{}) {
-	l.printf(infoSeverity, false, format, v...)
+	l.printf(InfoSeverity, false, format, v...)
 }
 
 // Warnf ...
 func (l *logger) Warnf(format string, v ...interface{}) {
-	l.printf(warnSeverity, false, format, v...)
+	l.printf(WarnSeverity, false, format, v...)
 }
 
 // Printf ...
 func (l *logger) Printf(format string, v ...interface{}) {
-	l.printf(normalSeverity, false, format, v...)
+	l.printf(NormalSeverity, false, format, v...)
 }
 
 // Donef ...
 func (l *logger) Donef(format string, v ...interface{}) {
-	l.printf(doneSeverity, false, format, v...)
+	l.printf(DoneSeverity, false, format, v...)
 }
 
 // Debugf ...
 func (l *logger) Debugf(format string, v ...interface{}) {
-	if l.enableDebugLog {
-		l.printf(debugSeverity, false, format, v...)
+	if l.debugEnabled() {
+		l.printf(DebugSeverity, false, format, v...)
 	}
 }
 
 // Errorf ...
 func (l *logger) Errorf(format string, v ...interface{}) {
-	l.printf(errorSeverity, false, format, v...)
+	l.printf(ErrorSeverity, false, format, v...)
 }
 
 // TInfof ...
 func (l *logger) TInfof(format string, v ...interface{}) {
-	l.printf(infoSeverity, true, format, v...)
+	l.printf(InfoSeverity, true, format, v...)
 }
 
 // TWarnf ...
 func (l *logger) TWarnf(format string, v ...interface{}) {
-	l.printf(warnSeverity, true, format, v...)
+	l.printf(WarnSeverity, true, format, v...)
 }
 
 // TPrintf ...
 func (l *logger) TPrintf(format string, v ...interface{}) {
-	l.printf(normalSeverity, true, format, v...)
+	l.printf(NormalSeverity, true, format, v...)
 }
 
 // TDonef ...
 func (l *logger) TDonef(format string, v ...interface{}) {
-	l.printf(doneSeverity, true, format, v...)
+	l.printf(DoneSeverity, true, format, v...)
 }
 
 // TDebugf ...
 func (l *logger) TDebugf(format string, v ...interface{}) {
-	if l.enableDebugLog {
-		l.printf(debugSeverity, true, format, v...)
+	if l.debugEnabled() {
+		l.printf(DebugSeverity, true, format, v...)
 	}
 }
 
 // TErrorf ...
 func (l *logger) TErrorf(format string, v ...interface{}) {
-	l.printf(errorSeverity, true, format, v...)
+	l.printf(ErrorSeverity, true, format, v...)
 }
 
-// Println ...
-func (l *logger) Println() {
-	if _, err := fmt.Fprintln(l.stdout); err != nil {
-		fmt.Printf("failed to print newline: %s\n", err)
-	}
+// With ...
+func (l *logger) With(keyvals ...interface{}) Logger {
+	clone := *l
+	clone.fields = append(append([]Field{}, l.fields...), fieldsFromKeyvals(keyvals)...)
+	return &clone
 }
 
-func (l *logger) timestampField() string {
-	currentTime := time.Now()
-	return fmt.Sprintf("[%s]", currentTime.Format(l.timestampLayout))
+// Info ...
+func (l *logger) Info(msg string, keyvals ...interface{}) {
+	l.log(InfoSeverity, msg, keyvals)
 }
 
-func (l *logger) prefixCurrentTime(message string) string {
-	return fmt.Sprintf("%s %s", l.timestampField(), message)
+// Warn ...
+func (l *logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(WarnSeverity, msg, keyvals)
 }
 
-func (l *logger) createLogMsg(severity Severity, withTime bool, format string, v ...interface{}) string {
-	colorFunc := severityColorFuncMap[severity]
-	message := colorFunc(format, v...)
-	if withTime {
-		message = l.prefixCurrentTime(message)
-	}
-	if l.prefix != "" {
-		message = l.prefix + message
+// Print ...
+func (l *logger) Print(msg string, keyvals ...interface{}) {
+	l.log(NormalSeverity, msg, keyvals)
+}
+
+// Done ...
+func (l *logger) Done(msg string, keyvals ...interface{}) {
+	l.log(DoneSeverity, msg, keyvals)
+}
+
+// Debug ...
+func (l *logger) Debug(msg string, keyvals ...interface{}) {
+	if l.debugEnabled() {
+		l.log(DebugSeverity, msg, keyvals)
 	}
+}
 
-	return message
+// Error ...
+func (l *logger) Error(msg string, keyvals ...interface{}) {
+	l.log(ErrorSeverity, msg, keyvals)
+}
+
+// Println ...
+func (l *logger) Println() {
+	l.forEachOutput(NormalSeverity, func(o output) {
+		if !o.human {
+			return
+		}
+		if _, err := fmt.Fprintln(o.writer); err != nil {
+			fmt.Printf("failed to print newline: %s\n", err)
+		}
+	})
+}
+
+func (l *logger) log(severity Severity, msg string, keyvals []interface{}) {
+	fields := l.fields
+	if len(keyvals) > 0 {
+		fields = append(append([]Field{}, l.fields...), fieldsFromKeyvals(keyvals)...)
+	}
+	l.write(Entry{Time: time.Now(), Severity: severity, Message: msg, Fields: fields})
 }
 
 func (l *logger) printf(severity Severity, withTime bool, format string, v ...interface{}) {
-	message := l.createLogMsg(severity, withTime, format, v...)
-	if _, err := fmt.Fprintln(l.stdout, message); err != nil {
-		fmt.Printf("failed to print message: %s: %s\n", message, err)
+	l.write(Entry{
+		Time:        time.Now(),
+		Severity:    severity,
+		Message:     fmt.Sprintf(format, v...),
+		Fields:      l.fields,
+		Timestamped: withTime,
+	})
+}
+
+func (l *logger) write(entry Entry) {
+	l.forEachOutput(entry.Severity, func(o output) {
+		line := o.encoder.Encode(entry)
+		if o.human {
+			line = indentLines(line, l.sectionIndent())
+		}
+		writeLine(o.writer, line)
+	})
+}
+
+func indentLines(text, indent string) string {
+	if indent == "" {
+		return text
 	}
+	return indent + strings.ReplaceAll(text, "\n", "\n"+indent)
 }
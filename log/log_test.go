@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/bitrise-io/go-utils/v2/log/colorstring"
 	"github.com/stretchr/testify/require"
 )
 
@@ -20,7 +21,7 @@ func TestSetOutWriter(t *testing.T) {
 func TestLogger_Options(t *testing.T) {
 	t.Run("With debug log enabled", func(t *testing.T) {
 		var b bytes.Buffer
-		logger := NewLogger(WithDebugLog(true), WithOutput(&b))
+		logger := NewLogger(WithDebugLog(true), WithOutput(&b), WithColorMode(colorstring.ModeAlways))
 		logger.Debugf("test %s", "log")
 		require.Equal(t, "\x1b[35;1mtest log\x1b[0m\n", b.String())
 	})
@@ -37,6 +38,61 @@ func TestLogger_Options(t *testing.T) {
 		re := regexp.MustCompile(`\[.+-.+-.+] test log`)
 		require.True(t, re.MatchString(b.String()), b.String())
 	})
+	t.Run("With color mode", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := NewLogger(WithOutput(&b), WithColorMode(colorstring.ModeNever))
+		logger.Errorf("test %s", "log")
+		logger.TWarnf("test")
+		require.Regexp(t, `^test log\n\[\d\d:\d\d:\d\d\] test\n$`, b.String())
+
+		b.Reset()
+		t.Setenv("NO_COLOR", "")
+		t.Setenv("FORCE_COLOR", "")
+		logger = NewLogger(WithOutput(&b), WithColorMode(colorstring.ModeAuto))
+		logger.Infof("not a terminal")
+		require.Equal(t, "not a terminal\n", b.String())
+
+		b.Reset()
+		t.Setenv("FORCE_COLOR", "1")
+		logger = NewLogger(WithOutput(&b), WithColorMode(colorstring.ModeAuto))
+		logger.Infof("forced")
+		require.Equal(t, "\x1b[34;1mforced\x1b[0m\n", b.String())
+
+		b.Reset()
+		t.Setenv("NO_COLOR", "1")
+		logger = NewLogger(WithOutput(&b))
+		logger.Infof("default mode")
+		require.Equal(t, "default mode\n", b.String())
+
+		b.Reset()
+		logger = NewLogger(WithOutput(&b), WithColorMode(colorstring.ModeAlways))
+		logger.Infof("always")
+		require.Equal(t, "\x1b[34;1malways\x1b[0m\n", b.String())
+
+		b.Reset()
+		t.Setenv("NO_COLOR", "")
+		t.Setenv("TERM", "dumb")
+		logger = NewLogger(WithOutput(&b), WithColorMode(colorstring.ModeAuto))
+		logger.Infof("forced on a dumb terminal")
+		require.Equal(t, "\x1b[34;1mforced on a dumb terminal\x1b[0m\n", b.String())
+	})
+	t.Run("With color mode, independent of the colorstring mode", func(t *testing.T) {
+		enabled := colorstring.Enabled()
+		defer func() {
+			if enabled {
+				colorstring.SetMode(colorstring.ModeAlways)
+			} else {
+				colorstring.SetMode(colorstring.ModeNever)
+			}
+		}()
+		colorstring.SetMode(colorstring.ModeNever)
+
+		var b bytes.Buffer
+		logger := NewLogger(WithOutput(&b), WithColorMode(colorstring.ModeAlways))
+		logger.Errorf("hello")
+		require.Equal(t, "\x1b[31;1mhello\x1b[0m\n", b.String())
+		require.Equal(t, "hello", colorstring.Red("hello"))
+	})
 	t.Run("With prefix", func(t *testing.T) {
 		var b bytes.Buffer
 		logger := NewLogger(WithOutput(&b), WithTimestampLayout("15-04-05"), WithPrefix("PREFIX"))
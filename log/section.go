@@ -0,0 +1,239 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+)
+
+// SectionInfo describes a log section for a SectionFormatter.
+type SectionInfo struct {
+	// ID is unique within the sections of a Logger, like "section_3_build".
+	ID    string
+	Title string
+	// Depth is 1 for a top level section, 2 for a section in a top level section, and so on.
+	Depth int
+	Start time.Time
+}
+
+// SectionFormatter prints the markers of the log sections, like the folding markers of a CI.
+type SectionFormatter interface {
+	// Begin returns the line printed at the start of section.
+	Begin(section SectionInfo) string
+	// End returns the line printed at the end of section.
+	End(section SectionInfo, elapsed time.Duration) string
+	// Indent returns the indentation of the lines printed in a section at depth.
+	Indent(depth int) string
+}
+
+// PlainSectionFormatter prints the sections with simple markers and indents their lines:
+//
+//	>> Install dependencies
+//	  ...
+//	<< Install dependencies (1.2s)
+type PlainSectionFormatter struct{}
+
+// Begin ...
+func (PlainSectionFormatter) Begin(section SectionInfo) string {
+	return ">> " + section.Title
+}
+
+// End ...
+func (PlainSectionFormatter) End(section SectionInfo, elapsed time.Duration) string {
+	return fmt.Sprintf("<< %s (%s)", section.Title, formatElapsed(elapsed))
+}
+
+// Indent ...
+func (PlainSectionFormatter) Indent(depth int) string {
+	return strings.Repeat("  ", depth)
+}
+
+// GitHubSectionFormatter prints the top level sections as GitHub Actions log groups (::group::).
+// GitHub Actions does not support nested groups, nested sections are printed by PlainSectionFormatter.
+type GitHubSectionFormatter struct{}
+
+// Begin ...
+func (GitHubSectionFormatter) Begin(section SectionInfo) string {
+	if section.Depth > 1 {
+		return PlainSectionFormatter{}.Begin(section)
+	}
+	return "::group::" + section.Title
+}
+
+// End ...
+func (GitHubSectionFormatter) End(section SectionInfo, elapsed time.Duration) string {
+	if section.Depth > 1 {
+		return PlainSectionFormatter{}.End(section, elapsed)
+	}
+	return fmt.Sprintf("::endgroup::\n%s finished in %s", section.Title, formatElapsed(elapsed))
+}
+
+// Indent ...
+func (GitHubSectionFormatter) Indent(depth int) string {
+	return PlainSectionFormatter{}.Indent(depth - 1)
+}
+
+// GitLabSectionFormatter prints the sections as GitLab CI collapsible sections, nested sections included.
+type GitLabSectionFormatter struct {
+	// Collapsed makes the sections collapsed by default.
+	Collapsed bool
+}
+
+// Begin ...
+func (f GitLabSectionFormatter) Begin(section SectionInfo) string {
+	options := ""
+	if f.Collapsed {
+		options = "[collapsed=true]"
+	}
+	return fmt.Sprintf("\x1b[0Ksection_start:%d:%s%s\r\x1b[0K%s", section.Start.Unix(), section.ID, options, section.Title)
+}
+
+// End ...
+func (GitLabSectionFormatter) End(section SectionInfo, elapsed time.Duration) string {
+	return fmt.Sprintf("\x1b[0Ksection_end:%d:%s\r\x1b[0K", section.Start.Add(elapsed).Unix(), section.ID)
+}
+
+// Indent ...
+func (GitLabSectionFormatter) Indent(depth int) string {
+	return PlainSectionFormatter{}.Indent(depth - 1)
+}
+
+// DetectSectionFormatter returns the SectionFormatter of the CI the process runs on, based on the environment:
+// GitHubSectionFormatter on GitHub Actions, GitLabSectionFormatter on GitLab CI, PlainSectionFormatter otherwise.
+func DetectSectionFormatter(envRepository env.Getter) SectionFormatter {
+	switch {
+	case envRepository.Get("GITHUB_ACTIONS") == "true":
+		return GitHubSectionFormatter{}
+	case envRepository.Get("GITLAB_CI") == "true":
+		return GitLabSectionFormatter{}
+	default:
+		return PlainSectionFormatter{}
+	}
+}
+
+// WithSectionFormatter sets the formatter of the log sections, defaults to PlainSectionFormatter.
+func WithSectionFormatter(formatter SectionFormatter) LoggerOptions {
+	return func(l *logger) {
+		l.sections.formatter = formatter
+	}
+}
+
+var sectionIDInvalidChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// sections is the stack of the open sections, shared by a Logger and the Loggers derived from it by With.
+type sections struct {
+	mux       sync.Mutex
+	formatter SectionFormatter
+	stack     []SectionInfo
+	counter   int
+}
+
+func newSections() *sections {
+	return &sections{formatter: PlainSectionFormatter{}}
+}
+
+func (s *sections) begin(title string) SectionInfo {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.counter++
+	id := strings.Trim(sectionIDInvalidChars.ReplaceAllString(strings.ToLower(title), "_"), "_")
+	section := SectionInfo{
+		ID:    fmt.Sprintf("section_%d_%s", s.counter, id),
+		Title: title,
+		Depth: len(s.stack) + 1,
+		Start: time.Now(),
+	}
+	s.stack = append(s.stack, section)
+	return section
+}
+
+func (s *sections) end() (SectionInfo, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if len(s.stack) == 0 {
+		return SectionInfo{}, false
+	}
+	section := s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+	return section, true
+}
+
+// indent returns the indentation of the lines printed in the current section.
+func (s *sections) indent() string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if len(s.stack) == 0 {
+		return ""
+	}
+	return s.formatter.Indent(len(s.stack))
+}
+
+// BeginSection ...
+func (l *logger) BeginSection(title string) {
+	l.ensureSections()
+
+	parentIndent := l.sections.indent()
+	section := l.sections.begin(title)
+	entry := Entry{Time: section.Start, Severity: NormalSeverity, Message: title, Fields: []Field{{Key: "section", Value: "begin"}}}
+	l.writeSectionMarker(entry, indentLines(l.sections.formatter.Begin(section), parentIndent))
+}
+
+// EndSection ...
+func (l *logger) EndSection() {
+	l.ensureSections()
+
+	section, ok := l.sections.end()
+	if !ok {
+		return
+	}
+	elapsed := time.Since(section.Start)
+	entry := Entry{Time: time.Now(), Severity: NormalSeverity, Message: section.Title, Fields: []Field{{Key: "section", Value: "end"}, {Key: "elapsed", Value: elapsed}}}
+	l.writeSectionMarker(entry, indentLines(l.sections.formatter.End(section, elapsed), l.sections.indent()))
+}
+
+// writeSectionMarker writes marker to the human readable outputs, and entry to the rest of the outputs.
+func (l *logger) writeSectionMarker(entry Entry, marker string) {
+	l.forEachOutput(entry.Severity, func(o output) {
+		if o.human {
+			writeLine(o.writer, marker)
+		} else {
+			writeLine(o.writer, o.encoder.Encode(entry))
+		}
+	})
+}
+
+// sectionIndent returns the indentation of the lines printed in the current section.
+func (l *logger) sectionIndent() string {
+	if l.sections == nil {
+		return ""
+	}
+	return l.sections.indent()
+}
+
+// Section ...
+func (l *logger) Section(title string, fn func()) {
+	l.BeginSection(title)
+	defer l.EndSection()
+
+	fn()
+}
+
+func (l *logger) ensureSections() {
+	if l.sections == nil {
+		l.sections = newSections()
+	}
+}
+
+func formatElapsed(elapsed time.Duration) string {
+	if elapsed >= time.Second {
+		return elapsed.Round(10 * time.Millisecond).String()
+	}
+	return elapsed.Round(time.Millisecond).String()
+}
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log/colorstring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Section(t *testing.T) {
+	t.Run("plain", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := NewLogger(WithOutput(&b))
+
+		logger.Printf("before")
+		logger.Section("Install dependencies", func() {
+			logger.Printf("npm ci")
+			logger.With("cache", "hit").Section("Restore cache", func() {
+				logger.Printf("line 1\nline 2")
+			})
+		})
+		logger.Printf("after")
+		logger.EndSection()
+
+		re := regexp.MustCompile(`^before
+>> Install dependencies
+  npm ci
+  >> Restore cache
+    line 1
+    line 2
+  << Restore cache \(\d+(\.\d+)?[µnm]?s\)
+<< Install dependencies \(\d+(\.\d+)?[µnm]?s\)
+after
+$`)
+		require.True(t, re.MatchString(b.String()), b.String())
+	})
+
+	t.Run("github", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := NewLogger(WithOutput(&b), WithSectionFormatter(GitHubSectionFormatter{}), WithColorMode(colorstring.ModeAlways))
+
+		logger.BeginSection("Build")
+		logger.Infof("compiling")
+		logger.BeginSection("Tests")
+		logger.Printf("ok")
+		logger.EndSection()
+		logger.EndSection()
+
+		re := regexp.MustCompile(`^::group::Build
+\x1b\[34;1mcompiling\x1b\[0m
+>> Tests
+  ok
+<< Tests \(.+\)
+::endgroup::
+Build finished in .+
+$`)
+		require.True(t, re.MatchString(b.String()), b.String())
+	})
+
+	t.Run("gitlab", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := NewLogger(WithOutput(&b), WithSectionFormatter(GitLabSectionFormatter{Collapsed: true}))
+
+		logger.Section("Build app", func() {
+			logger.Section("Tests", func() {
+				logger.Printf("ok")
+			})
+		})
+
+		re := regexp.MustCompile(`^\x1b\[0Ksection_start:\d+:section_1_build_app\[collapsed=true\]\r\x1b\[0KBuild app
+\x1b\[0Ksection_start:\d+:section_2_tests\[collapsed=true\]\r\x1b\[0KTests
+  ok
+\x1b\[0Ksection_end:\d+:section_2_tests\r\x1b\[0K
+\x1b\[0Ksection_end:\d+:section_1_build_app\r\x1b\[0K
+$`)
+		require.True(t, re.MatchString(b.String()), b.String())
+	})
+
+	t.Run("json encoder", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := NewLogger(WithOutput(&b), WithEncoder(JSONEncoder{}))
+
+		logger.Section("Build", func() {
+			logger.Printf("compiling")
+		})
+
+		lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+		require.Len(t, lines, 3)
+		require.Regexp(t, `"level":"normal","msg":"Build","section":"begin"}$`, lines[0])
+		require.Regexp(t, `"level":"normal","msg":"compiling"}$`, lines[1])
+		require.Regexp(t, `"level":"normal","msg":"Build","section":"end","elapsed":"[^"]+"}$`, lines[2])
+	})
+}
+
+func TestDetectSectionFormatter(t *testing.T) {
+	require.Equal(t, GitHubSectionFormatter{}, DetectSectionFormatter(env.NewMemoryRepository([]string{"GITHUB_ACTIONS=true"})))
+	require.Equal(t, GitLabSectionFormatter{}, DetectSectionFormatter(env.NewMemoryRepository([]string{"GITLAB_CI=true"})))
+	require.Equal(t, PlainSectionFormatter{}, DetectSectionFormatter(env.NewMemoryRepository(nil)))
+}
@@ -1,35 +1,90 @@
 package log
 
-import "github.com/bitrise-io/go-utils/v2/log/colorstring"
+import (
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/v2/log/colorstring"
+)
 
 // Severity ...
 type Severity uint8
 
+// The severities of the log entries. The order of the constants is not the order of importance,
+// see Severity.AtLeast. The zero Severity is not the severity of any entry, it ranks like DebugSeverity,
+// so the zero Sink.MinSeverity writes every entry.
 const (
-	errorSeverity Severity = iota
-	warnSeverity
-	normalSeverity
-	infoSeverity
-	doneSeverity
-	debugSeverity
+	// ErrorSeverity is the severity of Errorf and Error.
+	ErrorSeverity Severity = iota + 1
+	// WarnSeverity is the severity of Warnf and Warn.
+	WarnSeverity
+	// NormalSeverity is the severity of Printf and Print.
+	NormalSeverity
+	// InfoSeverity is the severity of Infof and Info.
+	InfoSeverity
+	// DoneSeverity is the severity of Donef and Done.
+	DoneSeverity
+	// DebugSeverity is the severity of Debugf and Debug.
+	DebugSeverity
 )
 
 type severityColorFunc colorstring.ColorfFunc
 
+// The severity colors are added by the Logger according to its own color mode (see WithColorMode),
+// they do not depend on the mode of the colorstring helpers (see colorstring.SetMode).
 var (
-	doneSeverityColorFunc   severityColorFunc = colorstring.Greenf
-	infoSeverityColorFunc   severityColorFunc = colorstring.Bluef
-	normalSeverityColorFunc severityColorFunc = colorstring.NoColorf
-	debugSeverityColorFunc  severityColorFunc = colorstring.Magentaf
-	warnSeverityColorFunc   severityColorFunc = colorstring.Yellowf
-	errorSeverityColorFunc  severityColorFunc = colorstring.Redf
+	doneSeverityColorFunc   = ansiColorFunc("\x1b[32;1m")
+	infoSeverityColorFunc   = ansiColorFunc("\x1b[34;1m")
+	normalSeverityColorFunc = severityColorFunc(colorstring.NoColorf)
+	debugSeverityColorFunc  = ansiColorFunc("\x1b[35;1m")
+	warnSeverityColorFunc   = ansiColorFunc("\x1b[33;1m")
+	errorSeverityColorFunc  = ansiColorFunc("\x1b[31;1m")
 )
 
+// ansiColorFunc returns a severityColorFunc which wraps the message in the given ANSI color escape sequence.
+func ansiColorFunc(color string) severityColorFunc {
+	return func(format string, a ...interface{}) string {
+		return color + fmt.Sprintf(format, a...) + "\x1b[0m"
+	}
+}
+
 var severityColorFuncMap = map[Severity]severityColorFunc{
-	doneSeverity:   doneSeverityColorFunc,
-	infoSeverity:   infoSeverityColorFunc,
-	normalSeverity: normalSeverityColorFunc,
-	debugSeverity:  debugSeverityColorFunc,
-	warnSeverity:   warnSeverityColorFunc,
-	errorSeverity:  errorSeverityColorFunc,
+	DoneSeverity:   doneSeverityColorFunc,
+	InfoSeverity:   infoSeverityColorFunc,
+	NormalSeverity: normalSeverityColorFunc,
+	DebugSeverity:  debugSeverityColorFunc,
+	WarnSeverity:   warnSeverityColorFunc,
+	ErrorSeverity:  errorSeverityColorFunc,
+}
+
+var severityNames = map[Severity]string{
+	ErrorSeverity:  "error",
+	WarnSeverity:   "warn",
+	NormalSeverity: "normal",
+	InfoSeverity:   "info",
+	DoneSeverity:   "done",
+	DebugSeverity:  "debug",
+}
+
+// String returns the name of the severity, like "info" or "error".
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", s)
+}
+
+// severityRanks orders the severities by importance, the normal, info and done severities rank the same.
+var severityRanks = map[Severity]int{
+	DebugSeverity:  0,
+	NormalSeverity: 1,
+	InfoSeverity:   1,
+	DoneSeverity:   1,
+	WarnSeverity:   2,
+	ErrorSeverity:  3,
+}
+
+// AtLeast reports whether s is at least as important as min: debug < normal = info = done < warn < error.
+// Every severity is at least as important as the zero Severity.
+func (s Severity) AtLeast(min Severity) bool {
+	return severityRanks[s] >= severityRanks[min]
 }
@@ -0,0 +1,212 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// Sink is an output of a SinkLogger.
+type Sink struct {
+	Writer io.Writer
+	// MinSeverity is the least important severity written to the sink (see Severity.AtLeast),
+	// for example InfoSeverity writes every entry except the debug ones. The zero value writes every entry.
+	MinSeverity Severity
+	// Encoder formats the entries, defaults to HumanEncoder configured by WithTimestampLayout and WithPrefix,
+	// with colors according to WithColorMode and Writer (with colorstring.ModeAuto files are written without colors).
+	Encoder Encoder
+}
+
+// SinkLogger is a Logger writing to multiple sinks.
+type SinkLogger interface {
+	Logger
+	// Flush flushes the writers of the sinks which buffer their output: the ones with a Flush() error
+	// (like bufio.Writer) or a Sync() error (like os.File) method.
+	Flush() error
+	// Close flushes the sinks, then closes their writers which are io.Closers, except os.Stdout and os.Stderr.
+	// The entries logged after Close are discarded.
+	Close() error
+}
+
+// NewSinkLogger returns a Logger which writes each entry to the sinks accepting its severity, for example:
+//
+//	logger := log.NewSinkLogger([]log.Sink{
+//		{Writer: os.Stdout, MinSeverity: log.InfoSeverity},
+//		{Writer: buildLogFile, MinSeverity: log.DebugSeverity},
+//		{Writer: jsonLogFile, MinSeverity: log.DebugSeverity, Encoder: log.JSONEncoder{}},
+//	}, log.WithColorMode(colorstring.ModeAuto))
+//	defer logger.Close()
+//
+// The sinks replace WithOutput and WithEncoder, and they filter the debug entries instead of WithDebugLog.
+// The Logger (and the Loggers derived from it by With) is safe for concurrent use, the lines of the entries
+// are not interleaved.
+func NewSinkLogger(sinks []Sink, options ...LoggerOptions) SinkLogger {
+	l := NewLogger(options...).(*logger)
+
+	set := &sinkSet{}
+	for _, sink := range sinks {
+		encoder := sink.Encoder
+		if encoder == nil {
+			encoder = HumanEncoder{
+				TimestampLayout: l.timestampLayout,
+				Prefix:          l.prefix,
+				NoColor:         !l.shouldColor(sink.Writer),
+			}
+		}
+		set.outputs = append(set.outputs, output{
+			writer:      sink.Writer,
+			minSeverity: sink.MinSeverity,
+			encoder:     encoder,
+			human:       isHumanEncoder(encoder),
+		})
+		if DebugSeverity.AtLeast(sink.MinSeverity) {
+			set.debug = true
+		}
+	}
+	l.sinks = set
+
+	return l
+}
+
+// output is a resolved Sink.
+type output struct {
+	writer      io.Writer
+	minSeverity Severity
+	encoder     Encoder
+	// human is set for HumanEncoder, which prints the section markers and indents the lines of the sections.
+	human bool
+}
+
+// sinkSet holds the outputs of a SinkLogger, shared by the Loggers derived from it by With.
+type sinkSet struct {
+	mux     sync.Mutex
+	outputs []output
+	debug   bool
+	closed  bool
+}
+
+// Flush ...
+func (l *logger) Flush() error {
+	if l.sinks == nil {
+		return flushWriter(l.stdout)
+	}
+
+	l.sinks.mux.Lock()
+	defer l.sinks.mux.Unlock()
+
+	return l.sinks.flush()
+}
+
+// Close ...
+func (l *logger) Close() error {
+	if l.sinks == nil {
+		return nil
+	}
+
+	l.sinks.mux.Lock()
+	defer l.sinks.mux.Unlock()
+
+	if l.sinks.closed {
+		return nil
+	}
+	l.sinks.closed = true
+
+	errs := []error{l.sinks.flush()}
+	var closed []io.Writer
+	for _, o := range l.sinks.outputs {
+		closer, ok := o.writer.(io.Closer)
+		if !ok || o.writer == io.Writer(os.Stdout) || o.writer == io.Writer(os.Stderr) || containsWriter(closed, o.writer) {
+			continue
+		}
+		closed = append(closed, o.writer)
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *sinkSet) flush() error {
+	var errs []error
+	for _, o := range s.outputs {
+		if err := flushWriter(o.writer); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func flushWriter(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Sync() error }:
+		if w == io.Writer(os.Stdout) || w == io.Writer(os.Stderr) {
+			// Syncing a terminal or a pipe fails, and there is nothing to sync.
+			return nil
+		}
+		return f.Sync()
+	}
+	return nil
+}
+
+// containsWriter reports whether writers contains w. Writers of uncomparable types are never found.
+func containsWriter(writers []io.Writer, w io.Writer) bool {
+	if !reflect.TypeOf(w).Comparable() {
+		return false
+	}
+	for _, writer := range writers {
+		if reflect.TypeOf(writer) == reflect.TypeOf(w) && writer == w {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachOutput calls fn with the outputs accepting severity, while holding the lock of the sinks.
+func (l *logger) forEachOutput(severity Severity, fn func(o output)) {
+	if l.sinks == nil {
+		encoder := l.encoder
+		if encoder == nil {
+			encoder = HumanEncoder{TimestampLayout: l.timestampLayout, Prefix: l.prefix, NoColor: l.noColor}
+		}
+		fn(output{writer: l.stdout, minSeverity: DebugSeverity, encoder: encoder, human: isHumanEncoder(encoder)})
+		return
+	}
+
+	l.sinks.mux.Lock()
+	defer l.sinks.mux.Unlock()
+
+	if l.sinks.closed {
+		return
+	}
+	for _, o := range l.sinks.outputs {
+		if severity.AtLeast(o.minSeverity) {
+			fn(o)
+		}
+	}
+}
+
+func (l *logger) debugEnabled() bool {
+	if l.sinks != nil {
+		return l.sinks.debug
+	}
+	return l.enableDebugLog
+}
+
+func isHumanEncoder(encoder Encoder) bool {
+	switch encoder.(type) {
+	case HumanEncoder, *HumanEncoder:
+		return true
+	}
+	return false
+}
+
+func writeLine(w io.Writer, line string) {
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		fmt.Printf("failed to print message: %s: %s\n", line, err)
+	}
+}
@@ -0,0 +1,137 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log/colorstring"
+	"github.com/stretchr/testify/require"
+)
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed int
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestSinkLogger(t *testing.T) {
+	var console, file, warnings, jsonFile bytes.Buffer
+	logger := NewSinkLogger([]Sink{
+		{Writer: &console, MinSeverity: InfoSeverity},
+		{Writer: &file, MinSeverity: DebugSeverity, Encoder: HumanEncoder{NoColor: true}},
+		{Writer: &warnings, MinSeverity: WarnSeverity, Encoder: HumanEncoder{NoColor: true}},
+		{Writer: &jsonFile, MinSeverity: DebugSeverity, Encoder: JSONEncoder{}},
+	}, WithColorMode(colorstring.ModeAlways))
+
+	logger.Debugf("debug %d", 1)
+	logger.Section("Build", func() {
+		logger.Donef("done")
+		logger.With("step", "test").Warn("warning")
+	})
+	logger.Println()
+	logger.Errorf("error")
+	logger.EnableDebugLog(false)
+	logger.Debug("debug 2")
+
+	require.Regexp(t, "^>> Build\n  \x1b\\[32;1mdone\x1b\\[0m\n  \x1b\\[33;1mwarning\x1b\\[0m step=test\n<< Build \\(.+\\)\n\n\x1b\\[31;1merror\x1b\\[0m\n$", console.String())
+	require.Regexp(t, "^debug 1\n>> Build\n  done\n  warning step=test\n<< Build \\(.+\\)\n\nerror\ndebug 2\n$", file.String())
+	require.Equal(t, "  warning step=test\nerror\n", warnings.String())
+
+	jsonLines := strings.Split(strings.TrimSpace(jsonFile.String()), "\n")
+	require.Len(t, jsonLines, 7)
+	require.Regexp(t, `"level":"debug","msg":"debug 1"}$`, jsonLines[0])
+	require.Regexp(t, `"level":"normal","msg":"Build","section":"begin"}$`, jsonLines[1])
+	require.Regexp(t, `"level":"debug","msg":"debug 2"}$`, jsonLines[6])
+}
+
+func TestSinkLogger_NoDebugSink(t *testing.T) {
+	var console bytes.Buffer
+	logger := NewSinkLogger([]Sink{{Writer: &console, MinSeverity: InfoSeverity}}, WithDebugLog(true))
+
+	logger.Debugf("hidden")
+	logger.Printf("visible")
+	require.Equal(t, "visible\n", console.String())
+}
+
+func TestSinkLogger_ZeroMinSeverity(t *testing.T) {
+	var file bytes.Buffer
+	logger := NewSinkLogger([]Sink{{Writer: &file}}, WithColorMode(colorstring.ModeNever))
+
+	logger.Debugf("debug")
+	logger.Printf("normal")
+	logger.Infof("info")
+	logger.Donef("done")
+	logger.Warnf("warn")
+	logger.Errorf("error")
+	require.Equal(t, "debug\nnormal\ninfo\ndone\nwarn\nerror\n", file.String())
+}
+
+func TestSinkLogger_FlushAndClose(t *testing.T) {
+	var buffered bytes.Buffer
+	bufferedWriter := bufio.NewWriter(&buffered)
+	file := &closeRecorder{}
+	logger := NewSinkLogger([]Sink{
+		{Writer: bufferedWriter, MinSeverity: DebugSeverity},
+		{Writer: file, MinSeverity: DebugSeverity, Encoder: JSONEncoder{}},
+		{Writer: file, MinSeverity: ErrorSeverity, Encoder: JSONEncoder{}},
+	})
+
+	logger.Printf("message")
+	require.Equal(t, "", buffered.String())
+	require.NoError(t, logger.Flush())
+	require.Equal(t, "message\n", buffered.String())
+
+	logger.Printf("last message")
+	require.NoError(t, logger.Close())
+	require.Equal(t, "message\nlast message\n", buffered.String())
+	require.Equal(t, 1, file.closed)
+
+	logger.Printf("discarded")
+	require.NoError(t, logger.Close())
+	require.Equal(t, "message\nlast message\n", buffered.String())
+	require.Equal(t, 2, strings.Count(file.String(), "\n"))
+}
+
+func TestSinkLogger_Concurrent(t *testing.T) {
+	var console, jsonFile bytes.Buffer
+	logger := NewSinkLogger([]Sink{
+		{Writer: &console, MinSeverity: InfoSeverity},
+		{Writer: &jsonFile, MinSeverity: DebugSeverity, Encoder: JSONEncoder{}},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			workerLogger := logger.With("worker", i)
+			for j := 0; j < 50; j++ {
+				workerLogger.Info(fmt.Sprintf("message %d", j))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, 500, strings.Count(console.String(), "\n"))
+	for _, line := range strings.Split(strings.TrimSpace(jsonFile.String()), "\n") {
+		require.Regexp(t, `^\{"time":"[^"]+","level":"info","msg":"message \d+","worker":\d+\}$`, line)
+	}
+}
+
+func TestSeverity_AtLeast(t *testing.T) {
+	require.True(t, ErrorSeverity.AtLeast(WarnSeverity))
+	require.True(t, DoneSeverity.AtLeast(InfoSeverity))
+	require.True(t, NormalSeverity.AtLeast(InfoSeverity))
+	require.False(t, DebugSeverity.AtLeast(InfoSeverity))
+	require.False(t, InfoSeverity.AtLeast(WarnSeverity))
+	require.True(t, DebugSeverity.AtLeast(DebugSeverity))
+	require.True(t, DebugSeverity.AtLeast(Severity(0)))
+}
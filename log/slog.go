@@ -0,0 +1,278 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// LevelDone is the slog level of the Done severity, between slog.LevelInfo and slog.LevelWarn.
+const LevelDone = slog.LevelInfo + 2
+
+// SlogHandlerOptions configures the handler returned by NewSlogHandler.
+type SlogHandlerOptions struct {
+	// Level is the minimum level of the records passed to the Logger, defaults to slog.LevelDebug.
+	// Debug records are printed only if debug logging is enabled on the Logger.
+	Level slog.Leveler
+}
+
+// NewSlogHandler returns a slog.Handler which writes the records through logger, so libraries using slog
+// print to the same output, in the same format:
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler(logger, nil)))
+//
+// The levels are mapped to the severities: slog.LevelError and above to Error, slog.LevelWarn and above to Warn,
+// LevelDone to Done, the rest of the levels from slog.LevelInfo to Info, and the lower levels to Debug.
+// The attributes become the fields of the entries, the keys of grouped attributes are prefixed with the group name
+// (like "request.method"). The time of the records is not used, the Logger stamps the entries.
+func NewSlogHandler(logger Logger, opts *SlogHandlerOptions) slog.Handler {
+	h := &slogHandler{logger: logger, level: slog.LevelDebug}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+type slogHandler struct {
+	logger Logger
+	level  slog.Leveler
+	fields []interface{}
+	group  string
+}
+
+// Enabled ...
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle ...
+func (h *slogHandler) Handle(_ context.Context, record slog.Record) error {
+	fields := append([]interface{}{}, h.fields...)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendAttr(fields, h.group, attr)
+		return true
+	})
+
+	switch {
+	case record.Level >= slog.LevelError:
+		h.logger.Error(record.Message, fields...)
+	case record.Level >= slog.LevelWarn:
+		h.logger.Warn(record.Message, fields...)
+	case record.Level == LevelDone:
+		h.logger.Done(record.Message, fields...)
+	case record.Level >= slog.LevelInfo:
+		h.logger.Info(record.Message, fields...)
+	default:
+		h.logger.Debug(record.Message, fields...)
+	}
+	return nil
+}
+
+// WithAttrs ...
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.fields = append([]interface{}{}, h.fields...)
+	for _, attr := range attrs {
+		clone.fields = appendAttr(clone.fields, h.group, attr)
+	}
+	return &clone
+}
+
+// WithGroup ...
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.group + name + "."
+	return &clone
+}
+
+// appendAttr appends attr as a Field, with its key prefixed by group. Groups are flattened, empty attributes are skipped.
+func appendAttr(fields []interface{}, group string, attr slog.Attr) []interface{} {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if value := attr.Value; value.Kind() == slog.KindGroup {
+		prefix := group
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, groupAttr := range value.Group() {
+			fields = appendAttr(fields, prefix, groupAttr)
+		}
+		return fields
+	}
+
+	return append(fields, Field{Key: group + attr.Key, Value: attr.Value.Any()})
+}
+
+// NewSlogLogger returns a Logger which writes through logger, so our tooling can print to a slog based output.
+// The severities are mapped to slog levels: Error and Warn to slog.LevelError and slog.LevelWarn,
+// Info and Print to slog.LevelInfo, Done to LevelDone and Debug to slog.LevelDebug.
+// The printf-style methods format the message, the timestamped variants log the same records (slog records have time),
+// Println is a no-op. Debug records are filtered by the handler of logger, unless EnableDebugLog(false) is called.
+// The start and end of the sections are logged as records with a section attribute ("begin" or "end").
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return &slogLogger{logger: logger, sections: newSections()}
+}
+
+type slogLogger struct {
+	logger       *slog.Logger
+	disableDebug bool
+	sections     *sections
+}
+
+// Infof ...
+func (l *slogLogger) Infof(format string, v ...interface{}) {
+	l.logf(slog.LevelInfo, format, v...)
+}
+
+// Warnf ...
+func (l *slogLogger) Warnf(format string, v ...interface{}) {
+	l.logf(slog.LevelWarn, format, v...)
+}
+
+// Printf ...
+func (l *slogLogger) Printf(format string, v ...interface{}) {
+	l.logf(slog.LevelInfo, format, v...)
+}
+
+// Donef ...
+func (l *slogLogger) Donef(format string, v ...interface{}) {
+	l.logf(LevelDone, format, v...)
+}
+
+// Debugf ...
+func (l *slogLogger) Debugf(format string, v ...interface{}) {
+	l.logf(slog.LevelDebug, format, v...)
+}
+
+// Errorf ...
+func (l *slogLogger) Errorf(format string, v ...interface{}) {
+	l.logf(slog.LevelError, format, v...)
+}
+
+// TInfof ...
+func (l *slogLogger) TInfof(format string, v ...interface{}) {
+	l.Infof(format, v...)
+}
+
+// TWarnf ...
+func (l *slogLogger) TWarnf(format string, v ...interface{}) {
+	l.Warnf(format, v...)
+}
+
+// TPrintf ...
+func (l *slogLogger) TPrintf(format string, v ...interface{}) {
+	l.Printf(format, v...)
+}
+
+// TDonef ...
+func (l *slogLogger) TDonef(format string, v ...interface{}) {
+	l.Donef(format, v...)
+}
+
+// TDebugf ...
+func (l *slogLogger) TDebugf(format string, v ...interface{}) {
+	l.Debugf(format, v...)
+}
+
+// TErrorf ...
+func (l *slogLogger) TErrorf(format string, v ...interface{}) {
+	l.Errorf(format, v...)
+}
+
+// Println ...
+func (l *slogLogger) Println() {}
+
+// EnableDebugLog ...
+func (l *slogLogger) EnableDebugLog(enable bool) {
+	l.disableDebug = !enable
+}
+
+// With ...
+func (l *slogLogger) With(keyvals ...interface{}) Logger {
+	return &slogLogger{logger: l.logger.With(slogArgs(keyvals)...), disableDebug: l.disableDebug, sections: l.sections}
+}
+
+// BeginSection ...
+func (l *slogLogger) BeginSection(title string) {
+	section := l.sections.begin(title)
+	l.logger.Log(context.Background(), slog.LevelInfo, section.Title, "section", "begin")
+}
+
+// EndSection ...
+func (l *slogLogger) EndSection() {
+	if section, ok := l.sections.end(); ok {
+		l.logger.Log(context.Background(), slog.LevelInfo, section.Title, "section", "end", "elapsed", time.Since(section.Start))
+	}
+}
+
+// Section ...
+func (l *slogLogger) Section(title string, fn func()) {
+	l.BeginSection(title)
+	defer l.EndSection()
+
+	fn()
+}
+
+// Info ...
+func (l *slogLogger) Info(msg string, keyvals ...interface{}) {
+	l.log(slog.LevelInfo, msg, keyvals)
+}
+
+// Warn ...
+func (l *slogLogger) Warn(msg string, keyvals ...interface{}) {
+	l.log(slog.LevelWarn, msg, keyvals)
+}
+
+// Print ...
+func (l *slogLogger) Print(msg string, keyvals ...interface{}) {
+	l.log(slog.LevelInfo, msg, keyvals)
+}
+
+// Done ...
+func (l *slogLogger) Done(msg string, keyvals ...interface{}) {
+	l.log(LevelDone, msg, keyvals)
+}
+
+// Debug ...
+func (l *slogLogger) Debug(msg string, keyvals ...interface{}) {
+	l.log(slog.LevelDebug, msg, keyvals)
+}
+
+// Error ...
+func (l *slogLogger) Error(msg string, keyvals ...interface{}) {
+	l.log(slog.LevelError, msg, keyvals)
+}
+
+func (l *slogLogger) logf(level slog.Level, format string, v ...interface{}) {
+	if level == slog.LevelDebug && l.disableDebug {
+		return
+	}
+	l.logger.Log(context.Background(), level, fmt.Sprintf(format, v...))
+}
+
+func (l *slogLogger) log(level slog.Level, msg string, keyvals []interface{}) {
+	if level == slog.LevelDebug && l.disableDebug {
+		return
+	}
+	l.logger.Log(context.Background(), level, msg, slogArgs(keyvals)...)
+}
+
+// slogArgs converts the Field values of keyvals to slog attributes, slog handles the rest of the key-value pairs.
+func slogArgs(keyvals []interface{}) []interface{} {
+	args := make([]interface{}, 0, len(keyvals))
+	for _, kv := range keyvals {
+		if field, ok := kv.(Field); ok {
+			args = append(args, slog.Any(field.Key, field.Value))
+			continue
+		}
+		args = append(args, kv)
+	}
+	return args
+}
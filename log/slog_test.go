@@ -0,0 +1,107 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log/colorstring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogHandler(t *testing.T) {
+	t.Run("levels and attributes", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := slog.New(NewSlogHandler(NewLogger(WithOutput(&b), WithColorMode(colorstring.ModeAlways)), nil))
+
+		logger.Info("Downloading", "url", "https://example.com", slog.Int("attempt", 1))
+		logger.Log(context.Background(), LevelDone, "Downloaded")
+		logger.With("component", "cache").WithGroup("request").Warn("Slow response", slog.Group("timing", "ms", 1200))
+		logger.Error("Failed", slog.Group("", "inline", true), slog.Attr{})
+		logger.Debug("Hidden")
+
+		require.Equal(t, "\x1b[34;1mDownloading\x1b[0m url=https://example.com attempt=1\n"+
+			"\x1b[32;1mDownloaded\x1b[0m\n"+
+			"\x1b[33;1mSlow response\x1b[0m component=cache request.timing.ms=1200\n"+
+			"\x1b[31;1mFailed\x1b[0m inline=true\n", b.String())
+	})
+
+	t.Run("levels around LevelDone", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := slog.New(NewSlogHandler(NewLogger(WithOutput(&b), WithColorMode(colorstring.ModeAlways)), nil))
+
+		logger.Log(context.Background(), slog.LevelInfo+1, "Notice")
+		logger.Log(context.Background(), LevelDone+1, "Important")
+
+		require.Equal(t, "\x1b[34;1mNotice\x1b[0m\n\x1b[34;1mImportant\x1b[0m\n", b.String())
+	})
+
+	t.Run("debug records", func(t *testing.T) {
+		var b bytes.Buffer
+		logger := slog.New(NewSlogHandler(NewLogger(WithOutput(&b), WithDebugLog(true), WithColorMode(colorstring.ModeAlways)), nil))
+		logger.Debug("Visible")
+		require.Equal(t, "\x1b[35;1mVisible\x1b[0m\n", b.String())
+
+		b.Reset()
+		logger = slog.New(NewSlogHandler(NewLogger(WithOutput(&b), WithDebugLog(true)), &SlogHandlerOptions{Level: slog.LevelInfo}))
+		logger.Debug("Filtered")
+		require.Equal(t, "", b.String())
+	})
+}
+
+func TestSlogLogger(t *testing.T) {
+	var b bytes.Buffer
+	handler := slog.NewJSONHandler(&b, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	logger := NewSlogLogger(slog.New(handler))
+
+	logger.With("step", "deploy", Field{Key: "app", Value: "web"}).Infof("Deploying %d files", 3)
+	logger.Done("Deployed", "took", "2s")
+	logger.TErrorf("Failed")
+	logger.Println()
+	logger.Debug("Details")
+	logger.EnableDebugLog(false)
+	logger.Debugf("Hidden")
+
+	var entries []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n")) {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &entry))
+		entries = append(entries, entry)
+	}
+	require.Equal(t, []map[string]interface{}{
+		{"level": "INFO", "msg": "Deploying 3 files", "step": "deploy", "app": "web"},
+		{"level": "INFO+2", "msg": "Deployed", "took": "2s"},
+		{"level": "ERROR", "msg": "Failed"},
+		{"level": "DEBUG", "msg": "Details"},
+	}, entries)
+}
+
+func TestSlogLogger_Section(t *testing.T) {
+	var b bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "elapsed" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+
+	logger.Section("Build", func() {
+		logger.Printf("compiling")
+	})
+
+	require.Equal(t, "level=INFO msg=Build section=begin\n"+
+		"level=INFO msg=compiling\n"+
+		"level=INFO msg=Build section=end\n", b.String())
+}
@@ -2,13 +2,29 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	log "github.com/bitrise-io/go-utils/v2/log"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Logger is an autogenerated mock type for the Logger type
 type Logger struct {
 	mock.Mock
 }
 
+// BeginSection provides a mock function with given fields: title
+func (_m *Logger) BeginSection(title string) {
+	_m.Called(title)
+}
+
+// Debug provides a mock function with given fields: msg, keyvals
+func (_m *Logger) Debug(msg string, keyvals ...interface{}) {
+	var _ca []interface{}
+	_ca = append(_ca, msg)
+	_ca = append(_ca, keyvals...)
+	_m.Called(_ca...)
+}
+
 // Debugf provides a mock function with given fields: format, v
 func (_m *Logger) Debugf(format string, v ...interface{}) {
 	var _ca []interface{}
@@ -17,6 +33,14 @@ func (_m *Logger) Debugf(format string, v ...interface{}) {
 	_m.Called(_ca...)
 }
 
+// Done provides a mock function with given fields: msg, keyvals
+func (_m *Logger) Done(msg string, keyvals ...interface{}) {
+	var _ca []interface{}
+	_ca = append(_ca, msg)
+	_ca = append(_ca, keyvals...)
+	_m.Called(_ca...)
+}
+
 // Donef provides a mock function with given fields: format, v
 func (_m *Logger) Donef(format string, v ...interface{}) {
 	var _ca []interface{}
@@ -30,6 +54,19 @@ func (_m *Logger) EnableDebugLog(enable bool) {
 	_m.Called(enable)
 }
 
+// EndSection provides a mock function with no fields
+func (_m *Logger) EndSection() {
+	_m.Called()
+}
+
+// Error provides a mock function with given fields: msg, keyvals
+func (_m *Logger) Error(msg string, keyvals ...interface{}) {
+	var _ca []interface{}
+	_ca = append(_ca, msg)
+	_ca = append(_ca, keyvals...)
+	_m.Called(_ca...)
+}
+
 // Errorf provides a mock function with given fields: format, v
 func (_m *Logger) Errorf(format string, v ...interface{}) {
 	var _ca []interface{}
@@ -38,6 +75,14 @@ func (_m *Logger) Errorf(format string, v ...interface{}) {
 	_m.Called(_ca...)
 }
 
+// Info provides a mock function with given fields: msg, keyvals
+func (_m *Logger) Info(msg string, keyvals ...interface{}) {
+	var _ca []interface{}
+	_ca = append(_ca, msg)
+	_ca = append(_ca, keyvals...)
+	_m.Called(_ca...)
+}
+
 // Infof provides a mock function with given fields: format, v
 func (_m *Logger) Infof(format string, v ...interface{}) {
 	var _ca []interface{}
@@ -46,6 +91,14 @@ func (_m *Logger) Infof(format string, v ...interface{}) {
 	_m.Called(_ca...)
 }
 
+// Print provides a mock function with given fields: msg, keyvals
+func (_m *Logger) Print(msg string, keyvals ...interface{}) {
+	var _ca []interface{}
+	_ca = append(_ca, msg)
+	_ca = append(_ca, keyvals...)
+	_m.Called(_ca...)
+}
+
 // Printf provides a mock function with given fields: format, v
 func (_m *Logger) Printf(format string, v ...interface{}) {
 	var _ca []interface{}
@@ -59,6 +112,11 @@ func (_m *Logger) Println() {
 	_m.Called()
 }
 
+// Section provides a mock function with given fields: title, fn
+func (_m *Logger) Section(title string, fn func()) {
+	_m.Called(title, fn)
+}
+
 // TDebugf provides a mock function with given fields: format, v
 func (_m *Logger) TDebugf(format string, v ...interface{}) {
 	var _ca []interface{}
@@ -107,6 +165,14 @@ func (_m *Logger) TWarnf(format string, v ...interface{}) {
 	_m.Called(_ca...)
 }
 
+// Warn provides a mock function with given fields: msg, keyvals
+func (_m *Logger) Warn(msg string, keyvals ...interface{}) {
+	var _ca []interface{}
+	_ca = append(_ca, msg)
+	_ca = append(_ca, keyvals...)
+	_m.Called(_ca...)
+}
+
 // Warnf provides a mock function with given fields: format, v
 func (_m *Logger) Warnf(format string, v ...interface{}) {
 	var _ca []interface{}
@@ -115,6 +181,28 @@ func (_m *Logger) Warnf(format string, v ...interface{}) {
 	_m.Called(_ca...)
 }
 
+// With provides a mock function with given fields: keyvals
+func (_m *Logger) With(keyvals ...interface{}) log.Logger {
+	var _ca []interface{}
+	_ca = append(_ca, keyvals...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for With")
+	}
+
+	var r0 log.Logger
+	if rf, ok := ret.Get(0).(func(...interface{}) log.Logger); ok {
+		r0 = rf(keyvals...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(log.Logger)
+		}
+	}
+
+	return r0
+}
+
 // NewLogger creates a new instance of Logger. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewLogger(t interface {
@@ -6,6 +6,8 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/env"
 )
 
 // PathProvider ...
@@ -84,14 +86,23 @@ type PathModifier interface {
 	EscapeGlobPath(path string) string
 }
 
-type pathModifier struct{}
+type pathModifier struct {
+	repository env.Getter
+}
 
 // NewPathModifier ...
 func NewPathModifier() PathModifier {
 	return pathModifier{}
 }
 
-// AbsPath expands ENV vars and the ~ character then calls Go's Abs
+// NewPathModifierWithRepository returns a PathModifier which expands the ENV vars from repository
+// instead of the process environment, with the shell syntax of env.Expand (like ${VAR:-default}).
+func NewPathModifierWithRepository(repository env.Getter) PathModifier {
+	return pathModifier{repository: repository}
+}
+
+// AbsPath expands ENV vars (with os.ExpandEnv, or env.Expand if created by NewPathModifierWithRepository)
+// and the ~ character then calls Go's Abs
 func (p pathModifier) AbsPath(pth string) (string, error) {
 	if pth == "" {
 		return "", errors.New("No Path provided")
@@ -102,7 +113,13 @@ func (p pathModifier) AbsPath(pth string) (string, error) {
 		return "", err
 	}
 
-	return filepath.Abs(os.ExpandEnv(pth))
+	if p.repository == nil {
+		pth = os.ExpandEnv(pth)
+	} else if pth, err = env.Expand(p.repository, pth, nil); err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(pth)
 }
 
 // EscapeGlobPath escapes glob special characters in the provided path string.
@@ -118,7 +135,14 @@ func (p pathModifier) EscapeGlobPath(path string) string {
 	return escaped
 }
 
-func (pathModifier) expandTilde(pth string) (string, error) {
+func (p pathModifier) envRepository() env.Getter {
+	if p.repository == nil {
+		return env.NewRepository()
+	}
+	return p.repository
+}
+
+func (p pathModifier) expandTilde(pth string) (string, error) {
 	if pth == "" {
 		return "", errors.New("No Path provided")
 	}
@@ -127,7 +151,7 @@ func (pathModifier) expandTilde(pth string) (string, error) {
 		pth = strings.TrimPrefix(pth, "~")
 
 		if len(pth) == 0 || strings.HasPrefix(pth, "/") {
-			return os.ExpandEnv("$HOME" + pth), nil
+			return p.envRepository().Get("HOME") + pth, nil
 		}
 
 		splitPth := strings.Split(pth, "/")
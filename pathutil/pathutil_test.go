@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/bitrise-io/go-utils/v2/env"
 	"github.com/stretchr/testify/require"
 )
 
@@ -236,6 +237,28 @@ func Test_pathModifier_AbsPath(t *testing.T) {
 	}
 }
 
+func Test_pathModifier_AbsPath_ExpandEnvCompatible(t *testing.T) {
+	t.Setenv("PROJECT", "app")
+	p := NewPathModifier()
+
+	// Like os.ExpandEnv: $$ and $1 are expanded as (empty) variables, an unterminated ${ is dropped.
+	got, err := p.AbsPath("/tmp/$PROJECT/a$$/b$1/c${")
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/app/a/b/c", got)
+}
+
+func Test_pathModifier_AbsPath_WithRepository(t *testing.T) {
+	repository := env.NewMemoryRepository([]string{"HOME=/home/repo-user", "PROJECT=app"})
+	p := NewPathModifierWithRepository(repository)
+
+	got, err := p.AbsPath("~/${PROJECT}/$$build")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("/home/repo-user", "app", "$build"), got)
+
+	_, err = p.AbsPath("${SOURCE_DIR:?is not set}/app")
+	require.EqualError(t, err, "SOURCE_DIR: is not set")
+}
+
 func Test_pathModifier_EscapeGlobPath(t *testing.T) {
 	tests := []struct {
 		name  string
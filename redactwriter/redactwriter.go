@@ -105,6 +105,12 @@ func (w *Writer) Write(p []byte) (int, error) {
 
 // Close implements io.Writer interface
 func (w *Writer) Close() error {
+	w.mux.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mux.Unlock()
+
 	_, err := w.flush()
 	return err
 }
@@ -125,6 +131,10 @@ func (w *Writer) flush() (int, error) {
 
 	// we only need to care about the full matches in the remaining lines
 	// (no more lines were come, why care about the partial matches?)
+	if len(w.store) == 0 {
+		return 0, nil
+	}
+
 	matchMap, _ := w.matchSecrets(w.store)
 	redactedLines := w.redact(w.store, matchMap)
 	w.store = nil